@@ -0,0 +1,372 @@
+// Package analytics derives key performance indicators from a schedule, per
+// vehicle and for the whole fleet, e.g. for dashboards:
+//
+//	report := analytics.Analyse(schedule, analytics.WithPlan(plan))
+//	b, err := json.Marshal(report)
+//
+// Times are in minutes and distances in km. Without the plan, depots are
+// told from visits by their place in the route, and loads are unknown.
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/slamethendry/routific"
+)
+
+// Report holds the indicators of a schedule.
+type Report struct {
+	Fleet    Fleet     `json:"fleet"`
+	Vehicles []Vehicle `json:"vehicles"` // sorted by ID
+}
+
+// Times splits the duration of routes, from leaving the start depot to
+// arriving at the end, into driving, waiting, service and breaks. Ratios are
+// fractions of the duration.
+type Times struct {
+	Duration     float64 `json:"route_duration"`
+	TravelTime   float64 `json:"travel_time"`
+	IdleTime     float64 `json:"idle_time"`
+	ServiceTime  float64 `json:"service_time"`
+	BreakTime    float64 `json:"break_time"`
+	TravelRatio  float64 `json:"travel_ratio"`
+	IdleRatio    float64 `json:"idle_ratio"`
+	ServiceRatio float64 `json:"service_ratio"`
+}
+
+// Vehicle holds the indicators of a vehicle's route.
+type Vehicle struct {
+	ID string `json:"vehicle"`
+	Times
+	Stops       int           `json:"stops"` // visits, pickups and dropoffs served
+	Breaks      int           `json:"breaks"`
+	Distance    float64       `json:"distance"`
+	LateStops   int           `json:"late_stops"`
+	Lateness    float64       `json:"lateness"` // total
+	MaxLateness float64       `json:"max_lateness"`
+	Overtime    float64       `json:"overtime"`
+	PeakLoad    routific.Load `json:"peak_load,omitempty"`
+	Capacity    routific.Load `json:"capacity,omitempty"`
+	Utilisation float64       `json:"utilisation_pct"` // of capacity at the peak load
+}
+
+// Fleet holds the indicators of all routes together.
+type Fleet struct {
+	Times
+	Vehicles         int            `json:"vehicles"`
+	VehiclesUsed     int            `json:"vehicles_used"` // with at least one stop
+	Stops            int            `json:"stops"`
+	StopsPerVehicle  float64        `json:"stops_per_vehicle"` // used
+	Distance         float64        `json:"distance"`
+	LateStops        int            `json:"late_stops"`
+	Lateness         float64        `json:"lateness"`         // total
+	AverageLateness  float64        `json:"average_lateness"` // of late stops
+	MaxLateness      float64        `json:"max_lateness"`
+	Distribution     []Bucket       `json:"lateness_distribution"`
+	Overtime         float64        `json:"overtime"`
+	Utilisation      float64        `json:"utilisation_pct"` // average of used vehicles with a capacity
+	Unserved         int            `json:"unserved"`
+	UnservedByReason map[string]int `json:"unserved_by_reason,omitempty"`
+}
+
+// Bucket counts the late stops that are late by From minutes or more, and
+// by less than To. To is 0 for the last bucket, which has no upper bound.
+type Bucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to,omitempty"`
+	Count int     `json:"count"`
+}
+
+// config holds the options of Analyse.
+type config struct {
+	plan    routific.Plan
+	buckets []float64
+}
+
+// Option configures Analyse.
+type Option func(*config)
+
+// WithPlan sets the plan of the schedule, a routific.VRPlan or
+// routific.PDPlan, to tell depots from visits by ID and to compute load
+// utilisation and overtime.
+func WithPlan(plan routific.Plan) Option {
+	return func(c *config) {
+		c.plan = plan
+	}
+}
+
+// WithLatenessBuckets sets the bounds in minutes between the buckets of the
+// lateness distribution, in any order. The default is 5, 15, 30 and 60.
+func WithLatenessBuckets(bounds ...float64) Option {
+	return func(c *config) {
+		c.buckets = append([]float64(nil), bounds...)
+		sort.Float64s(c.buckets)
+	}
+}
+
+// Analyse computes the indicators of a schedule.
+func Analyse(s routific.Schedule, opts ...Option) Report {
+
+	c := config{buckets: []float64{5, 15, 30, 60}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	report := Report{Vehicles: make([]Vehicle, 0, len(s.Solution))}
+	fleet := &report.Fleet
+
+	ids := make([]string, 0, len(s.Solution))
+	for id := range s.Solution {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Times are counted from midnight of the first day
+	timed := s.Materialize(time.Time{}, time.UTC)
+
+	var lateness []float64
+	var utilisation float64
+	var withCapacity int
+
+	for _, id := range ids {
+		v, late := c.vehicle(id, timed.Routes[id], s.Overtime[id])
+		report.Vehicles = append(report.Vehicles, v)
+		lateness = append(lateness, late...)
+
+		fleet.Vehicles++
+		if v.Stops > 0 {
+			fleet.VehiclesUsed++
+			if len(v.Capacity) > 0 {
+				utilisation += v.Utilisation
+				withCapacity++
+			}
+		}
+		fleet.Stops += v.Stops
+		fleet.Distance += v.Distance
+		fleet.LateStops += v.LateStops
+		fleet.Lateness += v.Lateness
+		fleet.Overtime += v.Overtime
+		if v.MaxLateness > fleet.MaxLateness {
+			fleet.MaxLateness = v.MaxLateness
+		}
+		fleet.Duration += v.Duration
+		fleet.TravelTime += v.TravelTime
+		fleet.IdleTime += v.IdleTime
+		fleet.ServiceTime += v.ServiceTime
+		fleet.BreakTime += v.BreakTime
+	}
+
+	fleet.Times.ratios()
+	fleet.StopsPerVehicle = ratio(float64(fleet.Stops), float64(fleet.VehiclesUsed))
+	fleet.AverageLateness = ratio(fleet.Lateness, float64(fleet.LateStops))
+	fleet.Distribution = distribution(lateness, c.buckets)
+	fleet.Utilisation = ratio(utilisation, float64(withCapacity))
+
+	fleet.Unserved = len(s.Unserved)
+	for _, reason := range s.Unserved {
+		if fleet.UnservedByReason == nil {
+			fleet.UnservedByReason = map[string]int{}
+		}
+		fleet.UnservedByReason[reason]++
+	}
+
+	return report
+}
+
+// vehicle computes the indicators of a route, and returns them with the
+// lateness of its late stops.
+func (c config) vehicle(id string, route []routific.TimedStop, overtime float32) (Vehicle, []float64) {
+
+	v := Vehicle{ID: id, Overtime: float64(overtime)}
+	if len(route) == 0 {
+		return v, nil
+	}
+
+	stops := make(routific.Stops, len(route))
+	for i := range route {
+		stops[i] = route[i].Stop
+	}
+
+	arrival, finish := minutes(route)
+	v.Duration = finish[len(stops)-1] - finish[0]
+
+	var late []float64
+	for i, stop := range stops {
+		v.Distance += float64(stop.Distance)
+
+		if stop.Break {
+			v.Breaks++
+			v.BreakTime += finish[i] - arrival[i]
+			continue
+		}
+		if !c.isVisit(id, stops, i) {
+			continue
+		}
+
+		v.Stops++
+		v.IdleTime += float64(stop.IdleTime)
+		v.ServiceTime += finish[i] - arrival[i] - float64(stop.IdleTime)
+		if stop.Late || stop.LateBy > 0 {
+			v.LateStops++
+			v.Lateness += float64(stop.LateBy)
+			late = append(late, float64(stop.LateBy))
+			if float64(stop.LateBy) > v.MaxLateness {
+				v.MaxLateness = float64(stop.LateBy)
+			}
+		}
+	}
+
+	v.TravelTime = v.Duration - v.IdleTime - v.ServiceTime - v.BreakTime
+	if v.TravelTime < 0 {
+		v.TravelTime = 0
+	}
+	v.Times.ratios()
+
+	if c.plan != nil {
+		c.load(&v, stops)
+		if vehicle, ok := c.plan.Vehicles()[id]; ok && vehicle.ShiftEnd != 0 {
+			end := float64(vehicle.ShiftEnd)
+			if vehicle.ShiftEnd < vehicle.ShiftStart {
+				end += 24 * 60 // shift past midnight
+			}
+			v.Overtime = 0
+			if last := finish[len(stops)-1]; last > end {
+				v.Overtime = last - end
+			}
+		}
+	}
+
+	return v, late
+}
+
+// minutes returns the arrival and finish times of a route in minutes since
+// midnight of its first day. Stops without a finish time finish when they
+// arrive.
+func minutes(route []routific.TimedStop) (arrival, finish []float64) {
+
+	arrival = make([]float64, len(route))
+	finish = make([]float64, len(route))
+
+	for i, stop := range route {
+		arrival[i] = stop.Arrival.Sub(time.Time{}).Minutes()
+		finish[i] = arrival[i]
+		if !stop.Finish.IsZero() {
+			finish[i] = stop.Finish.Sub(time.Time{}).Minutes()
+		}
+	}
+
+	return arrival, finish
+}
+
+// isVisit reports whether stops[i] is a visit, a pickup or a dropoff rather
+// than a depot. Without a plan, the first stop is the start depot, and the
+// last stop is the end depot if it has no finish time.
+func (c config) isVisit(vehicleID string, stops routific.Stops, i int) bool {
+
+	switch p := c.plan.(type) {
+	case routific.VRPlan:
+		_, ok := p.Visits[stops[i].ID]
+		return ok
+	case routific.PDPlan:
+		_, ok := p.Visits[stops[i].ID]
+		return ok
+	}
+
+	last := len(stops) - 1
+	return i > 0 && (i < last || stops[last].HasFinish())
+}
+
+// load sets the peak load and utilisation of the vehicle from the loads of
+// the plan: all VRP visits are loaded at the start, and PDP orders between
+// their pickup and dropoff.
+func (c config) load(v *Vehicle, stops routific.Stops) {
+
+	var load, peak routific.Load
+
+	switch p := c.plan.(type) {
+	case routific.VRPlan:
+		v.Capacity = p.Fleet[v.ID].Capacity
+		for _, stop := range stops {
+			if visit, ok := p.Visits[stop.ID]; ok && !stop.Break {
+				load = load.Add(visit.Load.OrUnit())
+			}
+		}
+		peak = load
+
+	case routific.PDPlan:
+		v.Capacity = p.Fleet[v.ID].Capacity
+		for _, stop := range stops {
+			order, ok := p.Visits[stop.ID]
+			if !ok || stop.Break {
+				continue
+			}
+			switch stop.Type {
+			case "pickup":
+				load = load.Add(order.Load.OrUnit())
+			case "dropoff":
+				load = load.Sub(order.Load.OrUnit())
+			}
+			peak = maxLoad(peak, load)
+		}
+	}
+
+	if !peak.IsZero() {
+		v.PeakLoad = peak
+	}
+	v.Utilisation = 100 * peak.Utilisation(v.Capacity)
+}
+
+// maxLoad returns the greatest quantity of a and b in every dimension.
+func maxLoad(a, b routific.Load) routific.Load {
+
+	out := a.Add(nil)
+	for dim, q := range b {
+		if q > out[dim] {
+			out[dim] = q
+		}
+	}
+
+	return out
+}
+
+// ratios sets the ratios of t from its times.
+func (t *Times) ratios() {
+	t.TravelRatio = ratio(t.TravelTime, t.Duration)
+	t.IdleRatio = ratio(t.IdleTime, t.Duration)
+	t.ServiceRatio = ratio(t.ServiceTime, t.Duration)
+}
+
+// ratio returns a / b, or 0 if b is 0.
+func ratio(a, b float64) float64 {
+
+	if b == 0 {
+		return 0
+	}
+
+	return a / b
+}
+
+// distribution counts the lateness values in the buckets between bounds.
+func distribution(lateness []float64, bounds []float64) []Bucket {
+
+	buckets := make([]Bucket, len(bounds)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].From = bounds[i-1]
+		}
+		if i < len(bounds) {
+			buckets[i].To = bounds[i]
+		}
+	}
+
+	for _, late := range lateness {
+		i := sort.SearchFloat64s(bounds, late)
+		if i < len(bounds) && bounds[i] == late {
+			i++ // bounds belong to the bucket above
+		}
+		buckets[i].Count++
+	}
+
+	return buckets
+}
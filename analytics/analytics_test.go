@@ -0,0 +1,229 @@
+package analytics_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/analytics"
+	"github.com/stretchr/testify/assert"
+)
+
+// analytics_test checks the indicators of a day with a break, late visits,
+// an unused vehicle and a route past midnight.
+
+var depot = r.Location{ID: "depot", Latitude: 49.2553636, Longitude: -123.0873365}
+
+var schedule = r.Schedule{
+	Solution: map[string]r.Stops{
+		"vehicle_1": {
+			{ID: "depot", ArrivalTime: r.Clock(8, 0)},
+			{ID: "order_1", ArrivalTime: r.Clock(8, 20), FinishTime: r.Clock(8, 40),
+				IdleTime: 5, Distance: 10},
+			{ID: "lunch", ArrivalTime: r.Clock(8, 40), FinishTime: r.Clock(9, 10),
+				Break: true},
+			{ID: "order_2", ArrivalTime: r.Clock(9, 30), FinishTime: r.Clock(9, 40),
+				Late: true, LateBy: 10, Distance: 12},
+			{ID: "depot", ArrivalTime: r.Clock(10, 0), Distance: 8},
+		},
+		"vehicle_2": {
+			{ID: "depot", ArrivalTime: r.Clock(8, 0)},
+			{ID: "depot", ArrivalTime: r.Clock(8, 0)},
+		},
+		"vehicle_3": {
+			{ID: "depot", ArrivalTime: r.Clock(23, 0)},
+			{ID: "order_3", ArrivalTime: r.Clock(23, 50), FinishTime: r.Clock(0, 10),
+				Late: true, LateBy: 20, Distance: 30},
+			{ID: "depot", ArrivalTime: r.Clock(0, 40), Distance: 30},
+		},
+	},
+	Unserved: map[string]string{
+		"order_4": "No vehicle available",
+		"order_5": "No vehicle available",
+		"order_6": "Outside time window",
+	},
+	Overtime: r.VehicleOvertime{"vehicle_1": 30},
+}
+
+var plan = r.VRPlan{
+	Visits: map[string]r.Visit{
+		"order_1": {Load: r.Units(2)},
+		"order_2": {},
+		"order_3": {Load: r.Units(1)},
+	},
+	Fleet: map[string]r.Vehicle{
+		"vehicle_1": {StartLocation: depot, EndLocation: depot,
+			ShiftEnd: r.Clock(9, 30), Capacity: r.Units(4)},
+		"vehicle_2": {StartLocation: depot, EndLocation: depot,
+			Capacity: r.Units(4)},
+		"vehicle_3": {StartLocation: depot, EndLocation: depot,
+			ShiftStart: r.Clock(22, 0), ShiftEnd: r.Clock(0, 30)},
+	},
+}
+
+func TestAnalyse(t *testing.T) {
+
+	report := analytics.Analyse(schedule)
+	assert.Len(t, report.Vehicles, 3)
+
+	v := report.Vehicles[0]
+	assert.Equal(t, "vehicle_1", v.ID)
+	assert.Equal(t, analytics.Times{
+		Duration:     120,
+		TravelTime:   60,
+		IdleTime:     5,
+		ServiceTime:  25,
+		BreakTime:    30,
+		TravelRatio:  0.5,
+		IdleRatio:    5.0 / 120,
+		ServiceRatio: 25.0 / 120,
+	}, v.Times)
+	assert.Equal(t, 2, v.Stops)
+	assert.Equal(t, 1, v.Breaks)
+	assert.Equal(t, 30.0, v.Distance)
+	assert.Equal(t, 1, v.LateStops)
+	assert.Equal(t, 10.0, v.Lateness)
+	assert.Equal(t, 30.0, v.Overtime)
+	assert.Nil(t, v.PeakLoad)
+
+	assert.Equal(t, 0, report.Vehicles[1].Stops)
+	assert.Equal(t, 0.0, report.Vehicles[1].Duration)
+
+	v = report.Vehicles[2]
+	assert.Equal(t, 100.0, v.Duration)
+	assert.Equal(t, 80.0, v.TravelTime)
+	assert.Equal(t, 20.0, v.ServiceTime)
+	assert.Equal(t, 1, v.Stops)
+
+	f := report.Fleet
+	assert.Equal(t, 3, f.Vehicles)
+	assert.Equal(t, 2, f.VehiclesUsed)
+	assert.Equal(t, 3, f.Stops)
+	assert.Equal(t, 1.5, f.StopsPerVehicle)
+	assert.Equal(t, 220.0, f.Duration)
+	assert.Equal(t, 140.0, f.TravelTime)
+	assert.Equal(t, 90.0, f.Distance)
+	assert.Equal(t, 2, f.LateStops)
+	assert.Equal(t, 30.0, f.Lateness)
+	assert.Equal(t, 15.0, f.AverageLateness)
+	assert.Equal(t, 20.0, f.MaxLateness)
+	assert.Equal(t, []analytics.Bucket{
+		{From: 0, To: 5},
+		{From: 5, To: 15, Count: 1},
+		{From: 15, To: 30, Count: 1},
+		{From: 30, To: 60},
+		{From: 60},
+	}, f.Distribution)
+	assert.Equal(t, 30.0, f.Overtime)
+	assert.Equal(t, 3, f.Unserved)
+	assert.Equal(t, map[string]int{
+		"No vehicle available": 2,
+		"Outside time window":  1,
+	}, f.UnservedByReason)
+}
+
+func TestAnalyseWithPlan(t *testing.T) {
+
+	report := analytics.Analyse(schedule, analytics.WithPlan(plan),
+		analytics.WithLatenessBuckets(15))
+
+	v := report.Vehicles[0]
+	assert.Equal(t, r.Units(3), v.PeakLoad)
+	assert.Equal(t, r.Units(4), v.Capacity)
+	assert.Equal(t, 75.0, v.Utilisation)
+	assert.Equal(t, 30.0, v.Overtime)
+
+	assert.Equal(t, 0.0, report.Vehicles[1].Utilisation)
+
+	v = report.Vehicles[2]
+	assert.Equal(t, 10.0, v.Overtime) // shift ends after midnight
+	assert.Equal(t, 0.0, v.Utilisation)
+
+	f := report.Fleet
+	assert.Equal(t, 75.0, f.Utilisation) // of the used vehicle with a capacity
+	assert.Equal(t, 40.0, f.Overtime)
+	assert.Equal(t, []analytics.Bucket{
+		{From: 0, To: 15, Count: 1},
+		{From: 15, Count: 1},
+	}, f.Distribution)
+
+	// Bounds in any order
+	unsorted := analytics.Analyse(schedule, analytics.WithLatenessBuckets(30, 15, 5))
+	assert.Equal(t, []analytics.Bucket{
+		{From: 0, To: 5},
+		{From: 5, To: 15, Count: 1},
+		{From: 15, To: 30, Count: 1},
+		{From: 30},
+	}, unsorted.Fleet.Distribution)
+
+	b, err := json.Marshal(report)
+	assert.Nil(t, err)
+	var out struct {
+		Fleet    map[string]interface{}   `json:"fleet"`
+		Vehicles []map[string]interface{} `json:"vehicles"`
+	}
+	assert.Nil(t, json.Unmarshal(b, &out))
+	assert.Equal(t, 75.0, out.Fleet["utilisation_pct"])
+	assert.Equal(t, 220.0, out.Fleet["route_duration"])
+	assert.Equal(t, "vehicle_1", out.Vehicles[0]["vehicle"])
+	assert.Equal(t, 3.0, out.Vehicles[0]["peak_load"])
+	assert.Equal(t, 0.5, out.Vehicles[0]["travel_ratio"])
+}
+
+func TestAnalyseEmpty(t *testing.T) {
+
+	report := analytics.Analyse(r.Schedule{})
+	assert.Equal(t, 0, report.Fleet.Vehicles)
+
+	b, err := json.Marshal(report)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"vehicles":[]`)
+}
+
+func TestAnalyseMidnight(t *testing.T) {
+
+	s := r.Schedule{Solution: map[string]r.Stops{"vehicle_1": {
+		{ID: "depot", ArrivalTime: r.Clock(23, 0)},
+		{ID: "order_1", ArrivalTime: r.Clock(23, 40), FinishAtMidnight: true},
+		{ID: "depot", ArrivalTime: r.Clock(0, 30)},
+	}}}
+
+	v := analytics.Analyse(s).Vehicles[0]
+	assert.Equal(t, 1, v.Stops)
+	assert.Equal(t, 90.0, v.Duration)
+	assert.Equal(t, 20.0, v.ServiceTime)
+	assert.Equal(t, 70.0, v.TravelTime)
+}
+
+func TestAnalysePDP(t *testing.T) {
+
+	plan := r.PDPlan{
+		Visits: map[string]r.PickDropOrder{
+			"order_1": {Load: r.Load{"weight": 30}},
+			"order_2": {Load: r.Load{"weight": 50, "volume": 1}},
+		},
+		Fleet: map[string]r.Vehicle{
+			"vehicle_1": {StartLocation: depot,
+				Capacity: r.Load{"weight": 100, "volume": 4}},
+		},
+	}
+	s := r.Schedule{Solution: map[string]r.Stops{"vehicle_1": {
+		{ID: "depot", ArrivalTime: r.Clock(8, 0)},
+		{ID: "order_1", Type: "pickup", ArrivalTime: r.Clock(8, 10), FinishTime: r.Clock(8, 15)},
+		{ID: "order_2", Type: "pickup", ArrivalTime: r.Clock(8, 20), FinishTime: r.Clock(8, 25)},
+		{ID: "order_1", Type: "dropoff", ArrivalTime: r.Clock(8, 40), FinishTime: r.Clock(8, 45)},
+		{ID: "order_2", Type: "dropoff", ArrivalTime: r.Clock(8, 50), FinishTime: r.Clock(8, 55)},
+	}}}
+
+	// The last stop is a dropoff, not a depot
+	report := analytics.Analyse(s)
+	assert.Equal(t, 4, report.Vehicles[0].Stops)
+
+	report = analytics.Analyse(s, analytics.WithPlan(plan))
+	v := report.Vehicles[0]
+	assert.Equal(t, 4, v.Stops)
+	assert.Equal(t, 55.0, v.Duration)
+	assert.Equal(t, 20.0, v.ServiceTime)
+	assert.Equal(t, r.Load{"weight": 80, "volume": 1}, v.PeakLoad)
+	assert.Equal(t, 80.0, v.Utilisation)
+}
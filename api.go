@@ -9,22 +9,57 @@
 package routific
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"time"
 )
 
-const vrpURL string = "https://api.routific.com/v1/vrp"
-const pdpURL string = "https://api.routific.com/v1/pdp"
-const vrpLongURL string = "https://api.routific.com/v1/vrp-long"
-const pdpLongURL string = "https://api.routific.com/v1/pdp-long"
-const longJobURL string = "https://api.routific.com/jobs"
-
 // VRP is a wrapper for Routific API for vehicle routing problem solver.
+// It is a shorthand for NewClient(token).VRP(visits).
 func VRP(visits VRPlan, token string) (Schedule, error) {
+	return NewClient(token).VRP(visits)
+}
+
+// PDP is a wrapper for Routific API for pickup-and-delivery problem solver.
+// It is a shorthand for NewClient(token).PDP(visits).
+func PDP(visits PDPlan, token string) (Schedule, error) {
+	return NewClient(token).PDP(visits)
+}
+
+// LongVRP is a wrapper for Routific API for long-running vehicle routing
+// problem solver.
+// It is a shorthand for NewClient(token).LongVRP(visits, interval, maxRetry).
+func LongVRP(
+	visits VRPlan,
+	token string,
+	interval uint16, // seconds
+	maxRetry uint8,
+) (Schedule, error) {
+	return NewClient(token).LongVRP(visits, interval, maxRetry)
+}
 
-	jsonOut, err := post(visits, vrpURL, token)
+// LongPDP is a wrapper for Routific API for long-running pickup-and-delivery
+// problem solver.
+// It is a shorthand for NewClient(token).LongPDP(visits, interval, maxRetry).
+func LongPDP(
+	visits PDPlan,
+	token string,
+	interval uint16, // seconds
+	maxRetry uint8,
+) (Schedule, error) {
+	return NewClient(token).LongPDP(visits, interval, maxRetry)
+}
+
+// VRP solves the vehicle routing problem.
+func (c *Client) VRP(visits VRPlan) (Schedule, error) {
+	return c.VRPContext(context.Background(), visits)
+}
+
+// VRPContext is like VRP but aborts the request when ctx is done.
+func (c *Client) VRPContext(ctx context.Context, visits VRPlan) (Schedule, error) {
+
+	jsonOut, err := c.post(ctx, visits, vrpPath)
 	if err != nil {
 		return Schedule{}, err
 	}
@@ -33,14 +68,20 @@ func VRP(visits VRPlan, token string) (Schedule, error) {
 	if err := json.Unmarshal(jsonOut, &plan); err != nil {
 		return Schedule{}, err
 	}
+	plan.MarkBreaks(visits.Fleet)
 
 	return plan, nil
 }
 
-// PDP is a wrapper for Routific API for pickup-and-delivery problem solver.
-func PDP(visits PDPlan, token string) (Schedule, error) {
+// PDP solves the pickup-and-delivery problem.
+func (c *Client) PDP(visits PDPlan) (Schedule, error) {
+	return c.PDPContext(context.Background(), visits)
+}
+
+// PDPContext is like PDP but aborts the request when ctx is done.
+func (c *Client) PDPContext(ctx context.Context, visits PDPlan) (Schedule, error) {
 
-	jsonOut, err := post(visits, pdpURL, token)
+	jsonOut, err := c.post(ctx, visits, pdpPath)
 	if err != nil {
 		return Schedule{}, err
 	}
@@ -49,110 +90,98 @@ func PDP(visits PDPlan, token string) (Schedule, error) {
 	if err := json.Unmarshal(jsonOut, &plan); err != nil {
 		return Schedule{}, err
 	}
+	plan.MarkBreaks(visits.Fleet)
 
 	return plan, nil
 }
 
-// LongVRP is a wrapper for Routific API for long-running vehicle routing
-// problem solver.
+// LongVRP solves the vehicle routing problem as a long-running job.
 // See [Interval]: https://docs.routific.com/reference/vrp-long to determine
 // how many seconds to wait according to the size of the input list.
 // If Routific server is not finished in (interval x maxRetry) seconds, then
-// the function returns empty schedule with error message ("Timed out").
-func LongVRP(
+// the function returns empty schedule with an error matching ErrTimeout.
+// Use SubmitVRPJob and WaitJob for finer control over the polling.
+func (c *Client) LongVRP(
 	visits VRPlan,
-	token string,
 	interval uint16, // seconds
 	maxRetry uint8,
 ) (Schedule, error) {
 
-	return longJob(visits, vrpLongURL, token, interval, maxRetry)
-	//return job.Output, err
+	return c.LongVRPContext(context.Background(), visits, interval, maxRetry)
 }
 
-// LongPDP is a wrapper for Routific API for long-running pickup-and-delivery
-// problem solver.
+// LongVRPContext is like LongVRP but stops waiting for the job, and aborts any
+// request in flight, when ctx is done.
+func (c *Client) LongVRPContext(
+	ctx context.Context,
+	visits VRPlan,
+	interval uint16, // seconds
+	maxRetry uint8,
+) (Schedule, error) {
+
+	return c.longJob(ctx, visits, visits.Fleet, vrpLongPath, interval, maxRetry)
+}
+
+// LongPDP solves the pickup-and-delivery problem as a long-running job.
 // See [Interval]: https://docs.routific.com/reference/vrp-long to determine
 // how many seconds to wait according to the size of the input list.
 // If Routific server is not finished in (interval x maxRetry) seconds, then
-// the function returns empty schedule with error message ("Timed out").
-func LongPDP(
+// the function returns empty schedule with an error matching ErrTimeout.
+// Use SubmitPDPJob and WaitJob for finer control over the polling.
+func (c *Client) LongPDP(
+	visits PDPlan,
+	interval uint16, // seconds
+	maxRetry uint8,
+) (Schedule, error) {
+
+	return c.LongPDPContext(context.Background(), visits, interval, maxRetry)
+}
+
+// LongPDPContext is like LongPDP but stops waiting for the job, and aborts any
+// request in flight, when ctx is done.
+func (c *Client) LongPDPContext(
+	ctx context.Context,
 	visits PDPlan,
-	token string,
 	interval uint16, // seconds
 	maxRetry uint8,
 ) (Schedule, error) {
 
-	return longJob(visits, pdpLongURL, token, interval, maxRetry)
-	//return job.Output, err
+	return c.longJob(ctx, visits, visits.Fleet, pdpLongPath, interval, maxRetry)
 }
 
-func longJob(
+func (c *Client) longJob(
+	ctx context.Context,
 	visits interface{},
-	url string,
-	token string,
+	fleet map[string]Vehicle,
+	path string,
 	interval uint16,
 	maxRetry uint8,
 ) (Schedule, error) {
 
-	jobJSON, err := post(visits, url, token)
+	job, err := c.submitJob(ctx, visits, path)
 	if err != nil {
 		return Schedule{}, err
 	}
 
-	var job struct {
-		ID string `json:"job_id"`
-	}
-
-	if err := json.Unmarshal(jobJSON, &job); err != nil {
-		return Schedule{}, err
-	}
-
-	jobURL := fmt.Sprintf("%s/%s", longJobURL, job.ID)
-
-	response, err := get(jobURL, token)
+	sched, err := c.WaitJob(ctx, job.ID, PollConfig{
+		Interval: time.Duration(interval) * time.Second,
+		MaxPolls: int(maxRetry) + 1,
+	})
 	if err != nil {
 		return Schedule{}, err
 	}
+	sched.MarkBreaks(fleet)
 
-	var check struct {
-		Status string `json:"status"`
-	}
-	if err := json.Unmarshal(response, &check); err != nil {
-		return Schedule{}, err
-	}
-
-	for try := uint8(0); check.Status != "finished" && try < maxRetry; try++ {
-		time.Sleep(time.Duration(interval) * time.Second)
-		response, err = get(jobURL, token)
-		if err := json.Unmarshal(response, &check); err != nil {
-			return Schedule{}, err
-		}
-	}
+	return sched, nil
+}
 
-	if check.Status == "finished" {
-		var plan struct {
-			Status string   `json:"status"`
-			ID     string   `json:"id"`
-			Output Schedule `json:"output,omitempty"`
-		}
-		if err := json.Unmarshal(response, &plan); err != nil {
-			return Schedule{}, err
-		}
-		return plan.Output, nil
-	}
+// withJobID records the job ID in err if it is an APIError.
+func withJobID(err error, id string) error {
 
-	if check.Status == "error" {
-		var errMsg struct {
-			Status string `json:"status"`
-			Output string `json:"output,omitempty"`
-		}
-		if err := json.Unmarshal(response, &errMsg); err != nil {
-			return Schedule{}, err
-		}
-		return Schedule{}, errors.New(errMsg.Output)
+	var e *APIError
+	if errors.As(err, &e) && e.JobID == "" {
+		e.JobID = id
 	}
 
-	e := fmt.Sprintf("Timed out after %d x %d seconds", maxRetry, interval)
-	return Schedule{}, errors.New(e)
+	return err
 }
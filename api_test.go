@@ -6,21 +6,39 @@ import (
 	"testing"
 
 	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/routifictest"
 	"github.com/stretchr/testify/assert"
 )
 
 // api_test calls Routific server API and compare the result
 // between input from JSON and input from Routific object.
-// Prerequisite: Auth Bearer Token, which, for testing purpose
-// only, is assumed to be available as environment variable
-// "Routific_Token".
+// With an Auth Bearer Token in the environment variable "Routific_Token",
+// it calls the real API; without one, e.g. in CI, a routifictest server.
 // Test data is defined in setup_test.
 
 var token = os.Getenv("Routific_Token")
 
+// apiClient returns a client of the real API if token is set, or else of a
+// routifictest server that solves plans with the test outputs at once.
+func apiClient(t *testing.T) *r.Client {
+
+	if token != "" {
+		return r.NewClient(token)
+	}
+
+	srv := routifictest.NewServer(
+		routifictest.WithVRPSchedule(vrpOutput),
+		routifictest.WithPDPSchedule(pdpOutput),
+		routifictest.WithJobScript(routifictest.JobStep{Status: r.JobFinished}),
+	)
+	t.Cleanup(srv.Close)
+
+	return srv.Client()
+}
+
 func TestVRP(t *testing.T) {
 
-	assert.NotEmpty(t, token)
+	c := apiClient(t)
 
 	// Compare the JSON conversion vs internally created object
 	var v r.VRPlan
@@ -29,13 +47,13 @@ func TestVRP(t *testing.T) {
 	assert.Equal(t, v, vrpInput)
 
 	// VRP call using JSON
-	output1, err := r.VRP(v, token)
+	output1, err := c.VRP(v)
 	assert.Nil(t, err)
 	assert.NotEmpty(t, output1)
 	assert.Equal(t, output1.Status, "success")
 
 	// VRP call using internally created object
-	output2, err := r.VRP(vrpInput, token)
+	output2, err := c.VRP(vrpInput)
 	assert.Nil(t, err)
 	assert.NotEmpty(t, output2)
 
@@ -44,7 +62,7 @@ func TestVRP(t *testing.T) {
 
 func TestPDP(t *testing.T) {
 
-	assert.NotEmpty(t, token)
+	c := apiClient(t)
 
 	// Compare the JSON conversion vs internally created object
 	var p r.PDPlan
@@ -53,13 +71,13 @@ func TestPDP(t *testing.T) {
 	assert.Equal(t, p, pdpInput)
 
 	// PDP call using JSON
-	output1, err := r.PDP(p, token)
+	output1, err := c.PDP(p)
 	assert.Nil(t, err)
 	assert.NotEmpty(t, output1)
 	assert.Equal(t, output1.Status, "success")
 
 	// PDP call using internally created object
-	output2, err := r.PDP(pdpInput, token)
+	output2, err := c.PDP(pdpInput)
 	assert.Nil(t, err)
 	assert.NotEmpty(t, output2)
 
@@ -68,9 +86,9 @@ func TestPDP(t *testing.T) {
 
 func TestLongVRP(t *testing.T) {
 
-	assert.NotEmpty(t, token)
+	c := apiClient(t)
 
-	vrp, err := r.LongVRP(vrpInput, token, 3, 5)
+	vrp, err := c.LongVRP(vrpInput, 3, 5)
 	assert.Nil(t, err)
 	assert.Equal(t, "success", vrp.Status)
 	assert.NotEmpty(t, vrp.Solution)
@@ -78,9 +96,9 @@ func TestLongVRP(t *testing.T) {
 
 func TestLongPDP(t *testing.T) {
 
-	assert.NotEmpty(t, token)
+	c := apiClient(t)
 
-	pdp, err := r.LongPDP(pdpInput, token, 3, 2)
+	pdp, err := c.LongPDP(pdpInput, 3, 2)
 	assert.Nil(t, err)
 	assert.Equal(t, "success", pdp.Status)
 	assert.NotEmpty(t, pdp.Solution)
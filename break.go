@@ -0,0 +1,57 @@
+package routific
+
+// Break is a driver break, e.g. lunch, for Routific to fit into a vehicle's
+// route. A break without a Duration lasts from Start to End. A break with a
+// Duration is flexible: it lasts Duration minutes, starting anywhere between
+// Start and End minus Duration.
+// See [Breaks]: https://docs.routific.com/reference/driver-breaks
+type Break struct {
+	ID       string    `json:"id"`
+	Start    ClockTime `json:"start"`
+	End      ClockTime `json:"end"`
+	Duration float32   `json:"duration,omitempty"` // minutes
+	Location *Location `json:"location,omitempty"` // where to take the break
+}
+
+// Length returns the minutes that b lasts.
+func (b Break) Length() float32 {
+
+	if b.Duration > 0 {
+		return b.Duration
+	}
+
+	return float32(b.End - b.Start)
+}
+
+// Breaks returns the breaks among the stops, in order.
+func (s Stops) Breaks() Stops {
+
+	var breaks Stops
+	for _, stop := range s {
+		if stop.Break {
+			breaks = append(breaks, stop)
+		}
+	}
+
+	return breaks
+}
+
+// MarkBreaks sets Stop.Break on the stops of the schedule that are breaks of
+// their vehicle in fleet, for schedules that do not flag breaks themselves.
+// The client calls it on every schedule it returns for a plan.
+func (s *Schedule) MarkBreaks(fleet map[string]Vehicle) {
+
+	for key, stops := range s.Solution {
+		breaks := fleet[key].Breaks
+		if len(breaks) == 0 {
+			continue
+		}
+		for i := range stops {
+			for _, b := range breaks {
+				if stops[i].ID == b.ID {
+					stops[i].Break = true
+				}
+			}
+		}
+	}
+}
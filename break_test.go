@@ -0,0 +1,117 @@
+package routific_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// break_test checks driver breaks in plans and schedules.
+// Test data is defined in setup_test.
+
+var lunch = r.Break{
+	ID:       "lunch",
+	Start:    r.Clock(12, 0),
+	End:      r.Clock(13, 30),
+	Duration: 30,
+}
+
+func TestBreakJSON(t *testing.T) {
+
+	var v r.Vehicle
+	err := json.Unmarshal([]byte(`{
+		"start_location": {"lat": 49.2553636, "lng": -123.0873365},
+		"breaks": [
+			{"id": "lunch", "start": "12:00", "end": "13:30", "duration": 30},
+			{"id": "fuel", "start": "15:00", "end": "15:15",
+			 "location": {"name": "Gas station", "lat": 49.25, "lng": -123.1}}
+		]
+	}`), &v)
+	assert.Nil(t, err)
+	assert.Equal(t, []r.Break{lunch, {
+		ID:       "fuel",
+		Start:    r.Clock(15, 0),
+		End:      r.Clock(15, 15),
+		Location: &r.Location{Name: "Gas station", Latitude: 49.25, Longitude: -123.1},
+	}}, v.Breaks)
+
+	assert.Equal(t, float32(30), v.Breaks[0].Length())
+	assert.Equal(t, float32(15), v.Breaks[1].Length())
+
+	var s r.Stop
+	err = json.Unmarshal([]byte(`{"location_id": "lunch",
+		"arrival_time": "12:10", "finish_time": "12:40", "break": true}`), &s)
+	assert.Nil(t, err)
+	assert.True(t, s.Break)
+}
+
+func TestMarkBreaks(t *testing.T) {
+
+	plan := vrpInput
+	plan.Fleet = map[string]r.Vehicle{}
+	for key, vehicle := range vrpInput.Fleet {
+		vehicle.Breaks = []r.Break{lunch}
+		plan.Fleet[key] = vehicle
+	}
+
+	// Schedule with a break that is not flagged
+	output := `{"status": "success", "num_unserved": 0, "solution": {
+		"vehicle_1": [
+			{"location_id": "depot", "arrival_time": "08:00"},
+			{"location_id": "order_1", "arrival_time": "11:00", "finish_time": "11:50"},
+			{"location_id": "lunch", "arrival_time": "12:00", "finish_time": "12:30"},
+			{"location_id": "order_2", "arrival_time": "12:45", "finish_time": "12:55"}
+		]}}`
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(output))
+		}))
+	defer srv.Close()
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL))
+	schedule, err := c.VRP(plan)
+	assert.Nil(t, err)
+
+	route := schedule.Solution["vehicle_1"]
+	assert.Equal(t, r.Stops{route[2]}, route.Breaks())
+	assert.Equal(t, "lunch", route[2].ID)
+	assert.True(t, route[2].Break)
+	assert.False(t, route[1].Break)
+}
+
+func TestValidateBreaks(t *testing.T) {
+
+	plan := vrpInput
+	plan.Fleet = map[string]r.Vehicle{
+		"vehicle_1": {
+			StartLocation: kingswayDepot,
+			Breaks: []r.Break{
+				lunch,
+				{Start: r.Clock(15, 0), End: r.Clock(15, 15), Duration: 20},
+				{ID: "order_1", Start: r.Clock(16, 0), End: r.Clock(15, 0)},
+				{ID: "coffee"},
+			},
+		},
+	}
+
+	var v *r.ValidationError
+	assert.True(t, errors.As(plan.Validate(), &v))
+
+	var got []string
+	for _, fe := range v.Errors {
+		got = append(got, fe.Error())
+	}
+	assert.Equal(t, []string{
+		"fleet[vehicle_1].breaks[1].id: missing",
+		"fleet[vehicle_1].breaks[1].duration: duration 20 does not fit between 15:00 and 15:15",
+		"fleet[vehicle_1].breaks[2].start: 16:00 is not before breaks[2].end 15:00",
+		"fleet[vehicle_1].breaks[3]: no start or end",
+		`fleet[vehicle_1].breaks[2].id: ID "order_1" is also used by a visit`,
+	}, got)
+}
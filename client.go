@@ -0,0 +1,110 @@
+package routific
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Routific Engine API endpoint used unless overridden
+// with WithBaseURL.
+const DefaultBaseURL string = "https://api.routific.com"
+
+// DefaultTimeout is the per-request timeout used unless overridden with
+// WithTimeout.
+const DefaultTimeout time.Duration = 3 * time.Second
+
+const vrpPath string = "/v1/vrp"
+const pdpPath string = "/v1/pdp"
+const vrpLongPath string = "/v1/vrp-long"
+const pdpLongPath string = "/v1/pdp-long"
+const jobPath string = "/jobs"
+
+// defaultHTTPClient is shared by clients that are not given their own, so
+// that connections are reused across calls.
+var defaultHTTPClient = &http.Client{}
+
+// Client calls the Routific Engine API with a given token.
+// A Client is safe for concurrent use and should be reused.
+type Client struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+	timeout    time.Duration
+	retry      RetryPolicy
+	retryPosts bool
+	validate   bool
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at a different API host, e.g. staging, a
+// proxy, or a local stand-in. The URL must not include the "/v1" prefix.
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(url, "/")
+	}
+}
+
+// WithHTTPClient sets the http.Client used for every request.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = h
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithTimeout sets the timeout of each individual HTTP request.
+// Zero means no timeout other than the one of the http.Client itself.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithValidation makes the client validate every plan before sending it, and
+// fail with a *ValidationError without calling the API if it is invalid.
+func WithValidation() Option {
+	return func(c *Client) {
+		c.validate = true
+	}
+}
+
+// NewClient returns a Client authenticating with token, configured by opts.
+func NewClient(token string, opts ...Option) *Client {
+
+	c := &Client{
+		token:      token,
+		baseURL:    DefaultBaseURL,
+		httpClient: defaultHTTPClient,
+		userAgent:  "routific-go",
+		timeout:    DefaultTimeout,
+		retry:      DefaultRetryPolicy(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = defaultHTTPClient
+	}
+	if c.retry == nil {
+		c.retry = NoRetry
+	}
+
+	return c
+}
+
+// url returns the absolute URL of an API path.
+func (c *Client) url(path string) string {
+	return c.baseURL + path
+}
@@ -0,0 +1,107 @@
+package routific_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// client_test checks that a configured Client sends its requests to the
+// given base URL with the expected headers, using a local stand-in server.
+// Test data is defined in setup_test.
+
+func TestClientOptions(t *testing.T) {
+
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			got = req
+			w.Write([]byte(vrpOutputJSON))
+		}))
+	defer srv.Close()
+
+	c := r.NewClient("secret",
+		r.WithBaseURL(srv.URL+"/"),
+		r.WithHTTPClient(srv.Client()),
+		r.WithUserAgent("dispatch/1.0"),
+		r.WithTimeout(time.Second),
+	)
+
+	output, err := c.VRP(vrpInput)
+	assert.Nil(t, err)
+	assert.Equal(t, vrpOutput, output)
+
+	assert.Equal(t, "POST", got.Method)
+	assert.Equal(t, "/v1/vrp", got.URL.Path)
+	assert.Equal(t, "bearer secret", got.Header.Get("Authorization"))
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, "dispatch/1.0", got.Header.Get("User-Agent"))
+}
+
+func TestClientTimeout(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(pdpOutputJSON))
+		}))
+	defer srv.Close()
+
+	c := r.NewClient("secret",
+		r.WithBaseURL(srv.URL),
+		r.WithTimeout(20*time.Millisecond),
+		r.WithRetryPolicy(r.NoRetry),
+	)
+
+	_, err := c.PDP(pdpInput)
+	assert.NotNil(t, err)
+}
+
+func TestClientContextCancel(t *testing.T) {
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			<-release
+		}))
+	defer srv.Close()
+	defer close(release)
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := c.VRPContext(ctx, vrpInput)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClientLongJobCancel(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == "POST" {
+				w.WriteHeader(202)
+				w.Write([]byte(`{"job_id": "abc"}`))
+				return
+			}
+			w.Write([]byte(`{"id": "abc", "status": "pending"}`))
+		}))
+	defer srv.Close()
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := c.LongPDPContext(ctx, pdpInput, 60, 10)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
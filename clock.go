@@ -0,0 +1,144 @@
+package routific
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minutesPerDay is the number of minutes in a day.
+const minutesPerDay = 24 * 60
+
+// ClockTime is a time of day, in minutes since midnight, written as "hh:mm"
+// in Routific's JSON.
+//
+// The zero value is midnight, which fields tagged omitempty leave out.
+// Routific reads a missing start or shift start as midnight anyway. Of the
+// times Routific returns, a stop's arrival is always written, and a finish
+// at midnight is told from none by Stop.FinishAtMidnight.
+type ClockTime int
+
+// Clock returns the ClockTime of the given hour and minute.
+func Clock(hour, minute int) ClockTime {
+	return ClockTime(hour*60 + minute)
+}
+
+// ClockOf returns the time of day of t, in t's location.
+func ClockOf(t time.Time) ClockTime {
+	return Clock(t.Hour(), t.Minute())
+}
+
+// ParseClockTime parses "hh:mm" or "h:mm" into a ClockTime, from "00:00" to
+// "23:59". The error matches ErrInvalidInput.
+func ParseClockTime(s string) (ClockTime, error) {
+
+	parts := strings.Split(s, ":")
+	if len(parts) == 2 && len(parts[0]) >= 1 && len(parts[0]) <= 2 &&
+		len(parts[1]) == 2 {
+		h, errH := strconv.Atoi(parts[0])
+		m, errM := strconv.Atoi(parts[1])
+		if errH == nil && errM == nil && h >= 0 && h < 24 && m >= 0 && m < 60 {
+			return Clock(h, m), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: time %q, want hh:mm", ErrInvalidInput, s)
+}
+
+// Valid reports whether t is within a day, i.e. from 00:00 to 23:59.
+func (t ClockTime) Valid() bool {
+	return t >= 0 && t < minutesPerDay
+}
+
+// Hour returns the hour of t, from 0 to 23 if t is valid.
+func (t ClockTime) Hour() int {
+	return int(t) / 60
+}
+
+// Minute returns the minute of t within the hour, from 0 to 59.
+func (t ClockTime) Minute() int {
+	return int(t) % 60
+}
+
+// String returns t as "hh:mm". An invalid t is written as is, e.g. "25:00".
+func (t ClockTime) String() string {
+
+	if t < 0 {
+		return "-" + (-t).String()
+	}
+
+	return fmt.Sprintf("%02d:%02d", t.Hour(), t.Minute())
+}
+
+// Add returns t+d, wrapping around midnight like a clock. Seconds are
+// truncated.
+func (t ClockTime) Add(d time.Duration) ClockTime {
+
+	m := (int(t) + int(d/time.Minute)) % minutesPerDay
+	if m < 0 {
+		m += minutesPerDay
+	}
+
+	return ClockTime(m)
+}
+
+// Sub returns the duration t-u, negative if t is before u.
+func (t ClockTime) Sub(u ClockTime) time.Duration {
+	return time.Duration(t-u) * time.Minute
+}
+
+// Before reports whether t is earlier in the day than u.
+func (t ClockTime) Before(u ClockTime) bool {
+	return t < u
+}
+
+// After reports whether t is later in the day than u.
+func (t ClockTime) After(u ClockTime) bool {
+	return t > u
+}
+
+// On returns t on the day of date in loc, which is UTC if nil. The wall
+// clock of loc is kept across daylight saving changes, and times from 24:00
+// fall on the next day.
+func (t ClockTime) On(date time.Time, loc *time.Location) time.Time {
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 0, int(t), 0, 0, loc)
+}
+
+// MarshalJSON writes t as "hh:mm".
+func (t ClockTime) MarshalJSON() ([]byte, error) {
+
+	if !t.Valid() {
+		return nil, fmt.Errorf("%w: time %s out of range", ErrInvalidInput, t)
+	}
+
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON reads t from "hh:mm" or "h:mm". An empty string is midnight.
+func (t *ClockTime) UnmarshalJSON(data []byte) error {
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: time %s, want \"hh:mm\"", ErrInvalidInput, data)
+	}
+
+	if s == "" {
+		*t = 0
+		return nil
+	}
+
+	c, err := ParseClockTime(s)
+	if err != nil {
+		return err
+	}
+
+	*t = c
+	return nil
+}
@@ -0,0 +1,94 @@
+package routific_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// clock_test checks parsing, formatting, and arithmetic of ClockTime.
+
+func TestParseClockTime(t *testing.T) {
+
+	for s, want := range map[string]r.ClockTime{
+		"0:00":  0,
+		"9:00":  r.Clock(9, 0),
+		"09:05": r.Clock(9, 5),
+		"23:59": r.Clock(23, 59),
+	} {
+		got, err := r.ParseClockTime(s)
+		assert.Nil(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	for _, s := range []string{"", "9", "9:5", "24:30", "12:60", "-1:00",
+		"123:00", "9:00:00", "nine"} {
+		_, err := r.ParseClockTime(s)
+		assert.True(t, errors.Is(err, r.ErrInvalidInput), s)
+	}
+}
+
+func TestClockTimeJSON(t *testing.T) {
+
+	var w r.TimeWindow
+	err := json.Unmarshal([]byte(`{"start": "9:00", "end": "17:30"}`), &w)
+	assert.Nil(t, err)
+	assert.Equal(t, r.TimeWindow{Start: r.Clock(9, 0), End: r.Clock(17, 30)}, w)
+
+	out, err := json.Marshal(w)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"start":"09:00","end":"17:30"}`, string(out))
+
+	var v r.Visit
+	err = json.Unmarshal([]byte(`{"start": "24:30"}`), &v)
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+	err = json.Unmarshal([]byte(`{"start": 930}`), &v)
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+
+	_, err = json.Marshal(r.Visit{End: r.Clock(24, 30)})
+	assert.NotNil(t, err)
+}
+
+func TestClockTimeArithmetic(t *testing.T) {
+
+	nine := r.Clock(9, 0)
+
+	assert.Equal(t, r.Clock(10, 30), nine.Add(90*time.Minute))
+	assert.Equal(t, r.Clock(1, 0), r.Clock(23, 0).Add(2*time.Hour))
+	assert.Equal(t, r.Clock(23, 0), r.Clock(1, 0).Add(-2*time.Hour))
+	assert.Equal(t, -90*time.Minute, nine.Sub(r.Clock(10, 30)))
+	assert.True(t, nine.Before(r.Clock(9, 1)))
+	assert.True(t, nine.After(r.Clock(8, 59)))
+	assert.Equal(t, 9, nine.Hour())
+	assert.Equal(t, 15, r.Clock(9, 15).Minute())
+	assert.Equal(t, "09:00", nine.String())
+	assert.False(t, r.Clock(24, 0).Valid())
+}
+
+func TestClockTimeOn(t *testing.T) {
+
+	vancouver, err := time.LoadLocation("America/Vancouver")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+
+	date := time.Date(2022, 3, 13, 0, 0, 0, 0, time.UTC) // DST starts at 2:00
+	at := r.Clock(9, 30).On(date, vancouver)
+
+	assert.Equal(t, "2022-03-13T09:30:00-07:00", at.Format(time.RFC3339))
+	assert.Equal(t, r.Clock(9, 30), r.ClockOf(at))
+
+	next := r.Clock(24, 15).On(date, vancouver)
+	assert.Equal(t, "2022-03-14T00:15:00-07:00", next.Format(time.RFC3339))
+}
+
+func TestClockTimeOnNil(t *testing.T) {
+
+	date := time.Date(2022, 3, 13, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2022, 3, 13, 9, 30, 0, 0, time.UTC),
+		r.Clock(9, 30).On(date, nil))
+}
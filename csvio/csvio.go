@@ -0,0 +1,376 @@
+// Package csvio reads Routific plans from CSV files, as kept in
+// spreadsheets, and writes schedules back out as CSV manifests.
+//
+// Columns are found by their header, ignoring case and surrounding spaces,
+// according to a column mapping such as DefaultVisitColumns. Apart from the
+// ID and coordinates, mapped columns may be missing from the file, and empty
+// cells leave their field unset, except for coordinates, which must be given.
+// Every malformed cell is reported in a *ParseError with its line number.
+package csvio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slamethendry/routific"
+)
+
+// RowError is a problem with a cell of a CSV file.
+type RowError struct {
+	Line   int    // 1 is the header
+	Column string // header of the column, or empty for the whole row
+	Err    error
+}
+
+func (e RowError) Error() string {
+
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("line %d: column %s: %v", e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError lists the problems of a CSV file.
+type ParseError struct {
+	Errors []RowError
+}
+
+func (e *ParseError) Error() string {
+
+	msgs := make([]string, len(e.Errors))
+	for i, re := range e.Errors {
+		msgs[i] = re.Error()
+	}
+
+	return fmt.Sprintf("csvio: %s", strings.Join(msgs, "; "))
+}
+
+// Unwrap returns routific.ErrInvalidInput.
+func (e *ParseError) Unwrap() error {
+	return routific.ErrInvalidInput
+}
+
+// table reads the rows of a CSV file and collects the problems of their
+// cells.
+type table struct {
+	r       *csv.Reader
+	columns map[string]int // by normalised header
+	ids     map[string]int // line of each ID
+	errs    []RowError
+}
+
+// newTable reads the header of the CSV file and checks that it has the
+// required columns.
+func newTable(in io.Reader, required ...string) (*table, error) {
+
+	t := &table{
+		r:       csv.NewReader(in),
+		columns: map[string]int{},
+		ids:     map[string]int{},
+	}
+	t.r.FieldsPerRecord = -1
+	t.r.TrimLeadingSpace = true
+
+	header, err := t.r.Read()
+	if err == io.EOF {
+		return nil, &ParseError{[]RowError{{Line: 1, Err: errors.New("no header")}}}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, h := range header {
+		t.columns[normalise(h)] = i
+	}
+	for _, col := range required {
+		if _, ok := t.columns[normalise(col)]; col != "" && !ok {
+			t.errs = append(t.errs, RowError{
+				Line: 1, Column: col, Err: errors.New("missing column")})
+		}
+	}
+
+	return t, t.err()
+}
+
+// next returns the next row, or nil at the end of the file.
+func (t *table) next() (*row, error) {
+
+	for {
+		rec, err := t.r.Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			t.errs = append(t.errs, RowError{Line: pe.StartLine, Err: pe.Err})
+			return nil, t.err()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := t.r.FieldPos(0)
+		if blank(rec) {
+			continue
+		}
+
+		return &row{t: t, line: line, rec: rec}, nil
+	}
+}
+
+// id returns the ID in column col of r, and reports it if it is missing or
+// was used on an earlier line.
+func (t *table) id(r *row, col string) string {
+
+	id := r.str(col)
+	switch first, dup := t.ids[id]; {
+	case id == "":
+		r.fail(col, errors.New("missing ID"))
+	case dup:
+		r.fail(col, fmt.Errorf("duplicate ID %q, first on line %d", id, first))
+	default:
+		t.ids[id] = r.line
+	}
+
+	return id
+}
+
+func (t *table) err() error {
+
+	if len(t.errs) == 0 {
+		return nil
+	}
+
+	return &ParseError{Errors: t.errs}
+}
+
+// row is a record of a table.
+type row struct {
+	t    *table
+	line int
+	rec  []string
+}
+
+func (r *row) fail(col string, err error) {
+	r.t.errs = append(r.t.errs, RowError{Line: r.line, Column: col, Err: err})
+}
+
+// str returns the trimmed cell of column col, or "" if the column is not
+// mapped or missing.
+func (r *row) str(col string) string {
+
+	if col == "" {
+		return ""
+	}
+	i, ok := r.t.columns[normalise(col)]
+	if !ok || i >= len(r.rec) {
+		return ""
+	}
+
+	return strings.TrimSpace(r.rec[i])
+}
+
+func (r *row) float(col string) float64 {
+
+	s := r.str(col)
+	if s == "" {
+		return 0
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		r.fail(col, fmt.Errorf("invalid number %q", s))
+	}
+
+	return f
+}
+
+func (r *row) int(col string) int {
+
+	s := r.str(col)
+	if s == "" {
+		return 0
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		r.fail(col, fmt.Errorf("invalid integer %q", s))
+	}
+
+	return n
+}
+
+func (r *row) bool(col string) bool {
+
+	s := r.str(col)
+	if s == "" {
+		return false
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		r.fail(col, fmt.Errorf("invalid boolean %q", s))
+	}
+
+	return b
+}
+
+func (r *row) clock(col string) routific.ClockTime {
+
+	s := r.str(col)
+	if s == "" {
+		return 0
+	}
+
+	t, err := routific.ParseClockTime(s)
+	if err != nil {
+		r.fail(col, fmt.Errorf("invalid time %q, want hh:mm", s))
+	}
+
+	return t
+}
+
+// coordinate reads a latitude or longitude, which unlike other numbers must
+// not be left empty: 0 is a valid coordinate, but rarely the intended one.
+func (r *row) coordinate(col string) float32 {
+
+	if r.str(col) == "" {
+		r.fail(col, errors.New("missing coordinate"))
+		return 0
+	}
+
+	return float32(r.float(col))
+}
+
+// location reads a location from the given columns.
+func (r *row) location(id, name, lat, lng string) routific.Location {
+	return routific.Location{
+		ID:        r.str(id),
+		Name:      r.str(name),
+		Latitude:  r.coordinate(lat),
+		Longitude: r.coordinate(lng),
+	}
+}
+
+// windows reads time windows written as "09:00-12:00", separated by ";".
+func (r *row) windows(col string) []routific.TimeWindow {
+
+	s := r.str(col)
+	if s == "" {
+		return nil
+	}
+
+	var windows []routific.TimeWindow
+	for _, part := range strings.Split(s, ";") {
+		start, end, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			r.fail(col, fmt.Errorf("invalid time window %q, want hh:mm-hh:mm", part))
+			continue
+		}
+		ts, errS := routific.ParseClockTime(strings.TrimSpace(start))
+		te, errE := routific.ParseClockTime(strings.TrimSpace(end))
+		if errS != nil || errE != nil {
+			r.fail(col, fmt.Errorf("invalid time window %q, want hh:mm-hh:mm", part))
+			continue
+		}
+		windows = append(windows, routific.TimeWindow{Start: ts, End: te})
+	}
+
+	return windows
+}
+
+// load reads a load from column col, either a number or dimensions written
+// as "weight=120;volume=3", and from the columns of dims by dimension.
+func (r *row) load(col string, dims map[string]string) routific.Load {
+
+	var load routific.Load
+
+	if s := r.str(col); s != "" {
+		if q, err := strconv.ParseFloat(s, 64); err == nil {
+			load = routific.Units(q)
+		} else {
+			load = routific.Load{}
+			for _, part := range strings.Split(s, ";") {
+				dim, v, ok := strings.Cut(part, "=")
+				q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if !ok || err != nil || strings.TrimSpace(dim) == "" {
+					r.fail(col, fmt.Errorf("invalid load %q, want a number or "+
+						"dimension=number pairs separated by ;", s))
+					return nil
+				}
+				load[strings.TrimSpace(dim)] = q
+			}
+		}
+	}
+
+	for _, dim := range sortedKeys(dims) {
+		if r.str(dims[dim]) == "" {
+			continue
+		}
+		if load == nil {
+			load = routific.Load{}
+		}
+		load[dim] = r.float(dims[dim])
+	}
+
+	if _, ok := load[""]; ok && len(load) > 1 {
+		r.fail(col, errors.New("load mixes a single quantity with dimensions"))
+	}
+
+	return load
+}
+
+// list reads values separated by ";".
+func (r *row) list(col string) []string {
+
+	s := r.str(col)
+	if s == "" {
+		return nil
+	}
+
+	var out []string
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+func normalise(header string) string {
+	return strings.ToLower(strings.TrimSpace(header))
+}
+
+func blank(rec []string) bool {
+
+	for _, s := range rec {
+		if strings.TrimSpace(s) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortedKeys(m map[string]string) []string {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,202 @@
+package csvio_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/csvio"
+	"github.com/stretchr/testify/assert"
+)
+
+// csvio_test checks reading plans from CSV and writing manifests.
+
+const visitsCSV = `ID, Name, Lat, Lng, Start, End, Time_Windows, Duration, Load, Type, Priority
+order_1,6800 Cambie,49.227107,-123.1163085,9:00,12:00,,10,1,,high
+order_2,3780 Arbutus,49.2474624,-123.1532338,,,09:00-10:00;14:00-15:00,5,weight=120;volume=3,truck,
+
+order_3,800 Robson,49.2819229,-123.1211844,,,,,,,
+`
+
+func TestReadVisits(t *testing.T) {
+
+	visits, err := csvio.ReadVisits(strings.NewReader(visitsCSV),
+		csvio.DefaultVisitColumns())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]r.Visit{
+		"order_1": {
+			Location: r.Location{Name: "6800 Cambie",
+				Latitude: 49.227107, Longitude: -123.1163085},
+			Start:    r.Clock(9, 0),
+			End:      r.Clock(12, 0),
+			Duration: 10,
+			Load:     r.Units(1),
+			Priority: "high",
+		},
+		"order_2": {
+			Location: r.Location{Name: "3780 Arbutus",
+				Latitude: 49.2474624, Longitude: -123.1532338},
+			TimeWindows: []r.TimeWindow{
+				{Start: r.Clock(9, 0), End: r.Clock(10, 0)},
+				{Start: r.Clock(14, 0), End: r.Clock(15, 0)},
+			},
+			Duration: 5,
+			Load:     r.Load{"weight": 120, "volume": 3},
+			Type:     "truck",
+		},
+		"order_3": {
+			Location: r.Location{Name: "800 Robson",
+				Latitude: 49.2819229, Longitude: -123.1211844},
+		},
+	}, visits)
+}
+
+func TestReadVisitsMapping(t *testing.T) {
+
+	const sheet = "Order,Latitude,Longitude,Kg,Litres\n" +
+		"A-1,49.2,-123.1,120,40\n"
+
+	cols := csvio.VisitColumns{
+		ID:             "Order",
+		Latitude:       "Latitude",
+		Longitude:      "Longitude",
+		LoadDimensions: map[string]string{"weight": "kg", "volume": "litres"},
+	}
+
+	visits, err := csvio.ReadVisits(strings.NewReader(sheet), cols)
+	assert.Nil(t, err)
+	assert.Equal(t, r.Load{"weight": 120, "volume": 40}, visits["A-1"].Load)
+}
+
+func TestReadVisitsErrors(t *testing.T) {
+
+	const sheet = `id,lat,lng,start,load,time_windows
+order_1,49.2,north,9:00,1,
+order_2,49.2,-123.1,25:00,,
+order_1,49.2,-123.1,,weight=heavy,
+,49.2,-123.1,,,9:00-8
+`
+
+	_, err := csvio.ReadVisits(strings.NewReader(sheet), csvio.DefaultVisitColumns())
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+
+	var pe *csvio.ParseError
+	assert.True(t, errors.As(err, &pe))
+
+	var got []string
+	for _, re := range pe.Errors {
+		got = append(got, re.Error())
+	}
+	assert.Equal(t, []string{
+		`line 2: column lng: invalid number "north"`,
+		`line 3: column start: invalid time "25:00", want hh:mm`,
+		`line 4: column id: duplicate ID "order_1", first on line 2`,
+		`line 4: column load: invalid load "weight=heavy", want a number or dimension=number pairs separated by ;`,
+		`line 5: column id: missing ID`,
+		`line 5: column time_windows: invalid time window "9:00-8", want hh:mm-hh:mm`,
+	}, got)
+	assert.Equal(t, 3, pe.Errors[1].Line)
+
+	// Missing required columns are reported on the header line
+	_, err = csvio.ReadVisits(strings.NewReader("id,name\n"), csvio.DefaultVisitColumns())
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, []csvio.RowError{
+		{Line: 1, Column: "lat", Err: pe.Errors[0].Err},
+		{Line: 1, Column: "lng", Err: pe.Errors[1].Err},
+	}, pe.Errors)
+	assert.EqualError(t, pe.Errors[0], "line 1: column lat: missing column")
+
+	// Empty coordinates are not taken to be 0
+	_, err = csvio.ReadVisits(strings.NewReader("id,lat,lng\na,,\n"),
+		csvio.DefaultVisitColumns())
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, []csvio.RowError{
+		{Line: 2, Column: "lat", Err: pe.Errors[0].Err},
+		{Line: 2, Column: "lng", Err: pe.Errors[1].Err},
+	}, pe.Errors)
+	assert.EqualError(t, pe.Errors[0], "line 2: column lat: missing coordinate")
+
+	// Malformed CSV
+	_, err = csvio.ReadVisits(strings.NewReader("id,lat,lng\n\"a,1,2\n"),
+		csvio.DefaultVisitColumns())
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, 2, pe.Errors[0].Line)
+}
+
+func TestReadFleet(t *testing.T) {
+
+	const sheet = `id,start_id,start_lat,start_lng,end_lat,end_lng,shift_start,shift_end,capacity,strict_start,min_visits
+vehicle_1,depot,49.2553636,-123.0873365,49.2553636,-123.0873365,08:00,17:00,300,true,
+vehicle_2,,49.2819229,-123.1211844,,,,,weight=1000;volume=20,,260
+`
+
+	fleet, err := csvio.ReadFleet(strings.NewReader(sheet), csvio.DefaultVehicleColumns())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]r.Vehicle{
+		"vehicle_1": {
+			StartLocation: r.Location{ID: "depot",
+				Latitude: 49.2553636, Longitude: -123.0873365},
+			EndLocation: r.Location{Latitude: 49.2553636, Longitude: -123.0873365},
+			ShiftStart:  r.Clock(8, 0),
+			ShiftEnd:    r.Clock(17, 0),
+			Capacity:    r.Units(300),
+			StrictStart: true,
+		},
+		"vehicle_2": {
+			StartLocation: r.Location{Latitude: 49.2819229, Longitude: -123.1211844},
+			Capacity:      r.Load{"weight": 1000, "volume": 20},
+			MinVisits:     260,
+		},
+	}, fleet)
+}
+
+func TestReadOrders(t *testing.T) {
+
+	const sheet = `id,load,type,pickup_lat,pickup_lng,pickup_start,pickup_duration,dropoff_name,dropoff_lat,dropoff_lng,dropoff_end
+order_1,2,van;truck,49.2474624,-123.1532338,09:00,10,6800 Cambie,49.227107,-123.1163085,12:00
+`
+
+	orders, err := csvio.ReadOrders(strings.NewReader(sheet), csvio.DefaultOrderColumns())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]r.PickDropOrder{
+		"order_1": {
+			Load: r.Units(2),
+			Type: []string{"van", "truck"},
+			PickUp: r.Destination{
+				Location: r.Location{Latitude: 49.2474624, Longitude: -123.1532338},
+				Start:    r.Clock(9, 0),
+				Duration: 10,
+			},
+			DropOff: r.Destination{
+				Location: r.Location{Name: "6800 Cambie",
+					Latitude: 49.227107, Longitude: -123.1163085},
+				End: r.Clock(12, 0),
+			},
+		},
+	}, orders)
+}
+
+func TestWriteManifest(t *testing.T) {
+
+	s := r.Schedule{Solution: map[string]r.Stops{
+		"vehicle_2": {{ID: "depot", ArrivalTime: r.Clock(8, 0)}},
+		"vehicle_1": {
+			{ID: "depot", Name: "800 Kingsway", ArrivalTime: r.Clock(8, 0)},
+			{ID: "order_1", Name: "6800 Cambie, rear", ArrivalTime: r.Clock(9, 5),
+				FinishTime: r.Clock(9, 15), Late: true, LateBy: 5.5},
+			{ID: "lunch", ArrivalTime: r.Clock(12, 0), FinishTime: r.Clock(12, 30),
+				Break: true},
+		},
+	}}
+
+	var b bytes.Buffer
+	assert.Nil(t, csvio.WriteManifest(&b, s))
+	assert.Equal(t, `vehicle,sequence,location_id,location_name,type,arrival,finish,late,late_by,break
+vehicle_1,0,depot,800 Kingsway,,08:00,,false,0,false
+vehicle_1,1,order_1,"6800 Cambie, rear",,09:05,09:15,true,5.5,false
+vehicle_1,2,lunch,,,12:00,12:30,false,0,true
+vehicle_2,0,depot,,,08:00,,false,0,false
+`, b.String())
+}
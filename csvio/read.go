@@ -0,0 +1,270 @@
+package csvio
+
+import (
+	"io"
+
+	"github.com/slamethendry/routific"
+)
+
+// VisitColumns maps the fields of a routific.Visit to CSV headers. Fields
+// mapped to "" are not read.
+type VisitColumns struct {
+	ID        string
+	Name      string
+	Latitude  string
+	Longitude string
+	Start     string
+	End       string
+
+	// TimeWindows holds windows such as "09:00-12:00;14:00-17:00".
+	TimeWindows string
+	Duration    string // minutes
+
+	// Load holds a number, or dimensions such as "weight=120;volume=3".
+	Load string
+	// LoadDimensions maps load dimensions to columns holding numbers.
+	LoadDimensions map[string]string
+
+	Type     string
+	Priority string
+	Notes    string
+}
+
+// DefaultVisitColumns returns the mapping of the headers id, name, lat, lng,
+// start, end, time_windows, duration, load, type, priority and notes.
+func DefaultVisitColumns() VisitColumns {
+	return VisitColumns{
+		ID:          "id",
+		Name:        "name",
+		Latitude:    "lat",
+		Longitude:   "lng",
+		Start:       "start",
+		End:         "end",
+		TimeWindows: "time_windows",
+		Duration:    "duration",
+		Load:        "load",
+		Type:        "type",
+		Priority:    "priority",
+		Notes:       "notes",
+	}
+}
+
+// ReadVisits reads the visits of a VRPlan, one per row, by ID.
+func ReadVisits(in io.Reader, cols VisitColumns) (map[string]routific.Visit, error) {
+
+	t, err := newTable(in, cols.ID, cols.Latitude, cols.Longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	visits := map[string]routific.Visit{}
+
+	for {
+		r, err := t.next()
+		if r == nil || err != nil {
+			return result(visits, err, t.err())
+		}
+
+		id := t.id(r, cols.ID)
+		visits[id] = routific.Visit{
+			Location:    r.location("", cols.Name, cols.Latitude, cols.Longitude),
+			Start:       r.clock(cols.Start),
+			End:         r.clock(cols.End),
+			TimeWindows: r.windows(cols.TimeWindows),
+			Duration:    float32(r.float(cols.Duration)),
+			Load:        r.load(cols.Load, cols.LoadDimensions),
+			Type:        r.str(cols.Type),
+			Priority:    r.str(cols.Priority),
+			Notes:       r.str(cols.Notes),
+		}
+	}
+}
+
+// VehicleColumns maps the fields of a routific.Vehicle to CSV headers.
+// Fields mapped to "" are not read. A vehicle has an end location if its
+// end coordinates are set.
+type VehicleColumns struct {
+	ID             string
+	StartID        string
+	StartName      string
+	StartLatitude  string
+	StartLongitude string
+	EndID          string
+	EndName        string
+	EndLatitude    string
+	EndLongitude   string
+	ShiftStart     string
+	ShiftEnd       string
+
+	// Capacity holds a number, or dimensions such as "weight=1000;volume=20".
+	Capacity string
+	// CapacityDimensions maps capacity dimensions to columns holding
+	// numbers.
+	CapacityDimensions map[string]string
+
+	Type        string
+	Speed       string
+	StrictStart string // true or false
+	MinVisits   string
+}
+
+// DefaultVehicleColumns returns the mapping of the headers id, start_id,
+// start_name, start_lat, start_lng, end_id, end_name, end_lat, end_lng,
+// shift_start, shift_end, capacity, type, speed, strict_start and
+// min_visits.
+func DefaultVehicleColumns() VehicleColumns {
+	return VehicleColumns{
+		ID:             "id",
+		StartID:        "start_id",
+		StartName:      "start_name",
+		StartLatitude:  "start_lat",
+		StartLongitude: "start_lng",
+		EndID:          "end_id",
+		EndName:        "end_name",
+		EndLatitude:    "end_lat",
+		EndLongitude:   "end_lng",
+		ShiftStart:     "shift_start",
+		ShiftEnd:       "shift_end",
+		Capacity:       "capacity",
+		Type:           "type",
+		Speed:          "speed",
+		StrictStart:    "strict_start",
+		MinVisits:      "min_visits",
+	}
+}
+
+// ReadFleet reads the fleet of a plan, one vehicle per row, by ID.
+func ReadFleet(in io.Reader, cols VehicleColumns) (map[string]routific.Vehicle, error) {
+
+	t, err := newTable(in, cols.ID, cols.StartLatitude, cols.StartLongitude)
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := map[string]routific.Vehicle{}
+
+	for {
+		r, err := t.next()
+		if r == nil || err != nil {
+			return result(fleet, err, t.err())
+		}
+
+		id := t.id(r, cols.ID)
+		v := routific.Vehicle{
+			StartLocation: r.location(cols.StartID, cols.StartName,
+				cols.StartLatitude, cols.StartLongitude),
+			ShiftStart:  r.clock(cols.ShiftStart),
+			ShiftEnd:    r.clock(cols.ShiftEnd),
+			Capacity:    r.load(cols.Capacity, cols.CapacityDimensions),
+			Type:        r.str(cols.Type),
+			Speed:       r.str(cols.Speed),
+			StrictStart: r.bool(cols.StrictStart),
+			MinVisits:   r.int(cols.MinVisits),
+		}
+		if r.str(cols.EndLatitude) != "" || r.str(cols.EndLongitude) != "" {
+			v.EndLocation = r.location(cols.EndID, cols.EndName,
+				cols.EndLatitude, cols.EndLongitude)
+		}
+		fleet[id] = v
+	}
+}
+
+// DestinationColumns maps the fields of a routific.Destination to CSV
+// headers.
+type DestinationColumns struct {
+	Name      string
+	Latitude  string
+	Longitude string
+	Start     string
+	End       string
+	Duration  string // minutes
+}
+
+// OrderColumns maps the fields of a routific.PickDropOrder to CSV headers.
+// Fields mapped to "" are not read.
+type OrderColumns struct {
+	ID      string
+	PickUp  DestinationColumns
+	DropOff DestinationColumns
+
+	// Load holds a number, or dimensions such as "weight=120;volume=3".
+	Load string
+	// LoadDimensions maps load dimensions to columns holding numbers.
+	LoadDimensions map[string]string
+
+	// Type holds vehicle types separated by ";".
+	Type string
+}
+
+// DefaultOrderColumns returns the mapping of the headers id, load, type, and
+// pickup_ and dropoff_ followed by name, lat, lng, start, end and duration.
+func DefaultOrderColumns() OrderColumns {
+
+	destination := func(prefix string) DestinationColumns {
+		return DestinationColumns{
+			Name:      prefix + "name",
+			Latitude:  prefix + "lat",
+			Longitude: prefix + "lng",
+			Start:     prefix + "start",
+			End:       prefix + "end",
+			Duration:  prefix + "duration",
+		}
+	}
+
+	return OrderColumns{
+		ID:      "id",
+		PickUp:  destination("pickup_"),
+		DropOff: destination("dropoff_"),
+		Load:    "load",
+		Type:    "type",
+	}
+}
+
+// ReadOrders reads the orders of a PDPlan, one per row, by ID.
+func ReadOrders(in io.Reader, cols OrderColumns) (map[string]routific.PickDropOrder, error) {
+
+	t, err := newTable(in, cols.ID,
+		cols.PickUp.Latitude, cols.PickUp.Longitude,
+		cols.DropOff.Latitude, cols.DropOff.Longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := map[string]routific.PickDropOrder{}
+
+	for {
+		r, err := t.next()
+		if r == nil || err != nil {
+			return result(orders, err, t.err())
+		}
+
+		id := t.id(r, cols.ID)
+		orders[id] = routific.PickDropOrder{
+			Load:    r.load(cols.Load, cols.LoadDimensions),
+			PickUp:  r.destination(cols.PickUp),
+			DropOff: r.destination(cols.DropOff),
+			Type:    r.list(cols.Type),
+		}
+	}
+}
+
+func (r *row) destination(cols DestinationColumns) routific.Destination {
+	return routific.Destination{
+		Location: r.location("", cols.Name, cols.Latitude, cols.Longitude),
+		Start:    r.clock(cols.Start),
+		End:      r.clock(cols.End),
+		Duration: float32(r.float(cols.Duration)),
+	}
+}
+
+// result returns m, or nil and the first error if there is one.
+func result[T any](m map[string]T, errs ...error) (map[string]T, error) {
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,61 @@
+package csvio
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/slamethendry/routific"
+)
+
+// ManifestHeader is the header of the CSV files written by WriteManifest.
+var ManifestHeader = []string{
+	"vehicle", "sequence", "location_id", "location_name", "type",
+	"arrival", "finish", "late", "late_by", "break",
+}
+
+// WriteManifest writes the stops of the schedule as CSV, one per row, by
+// vehicle ID and then in route order. Sequence 0 is the start of the route.
+// Times are written as hh:mm, and the finish is empty for stops without one.
+func WriteManifest(w io.Writer, s routific.Schedule) error {
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(ManifestHeader); err != nil {
+		return err
+	}
+
+	vehicles := make([]string, 0, len(s.Solution))
+	for vehicle := range s.Solution {
+		vehicles = append(vehicles, vehicle)
+	}
+	sort.Strings(vehicles)
+
+	for _, vehicle := range vehicles {
+		for i, stop := range s.Solution[vehicle] {
+			finish := ""
+			if stop.HasFinish() {
+				finish = stop.FinishTime.String()
+			}
+			err := cw.Write([]string{
+				vehicle,
+				strconv.Itoa(i),
+				stop.ID,
+				stop.Name,
+				stop.Type,
+				stop.ArrivalTime.String(),
+				finish,
+				strconv.FormatBool(stop.Late),
+				strconv.FormatFloat(float64(stop.LateBy), 'f', -1, 32),
+				strconv.FormatBool(stop.Break),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,122 @@
+package routific
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors, to be checked with errors.Is against the errors returned
+// by the API calls.
+var (
+	// ErrUnauthorized means the token is missing, invalid, or lacks access.
+	ErrUnauthorized = errors.New("routific: unauthorized")
+	// ErrRateLimited means too many requests were sent.
+	ErrRateLimited = errors.New("routific: rate limited")
+	// ErrInvalidInput means Routific rejected the plan.
+	ErrInvalidInput = errors.New("routific: invalid input")
+	// ErrJobFailed means a long-running job finished with status "error".
+	ErrJobFailed = errors.New("routific: job failed")
+	// ErrTimeout means a long-running job did not finish in time.
+	ErrTimeout = errors.New("routific: timed out")
+	// ErrJobNotFinished means the result of a long-running job was asked
+	// for before the job finished.
+	ErrJobNotFinished = errors.New("routific: job not finished")
+	// ErrNotSupported means a Solver cannot solve this kind of plan.
+	ErrNotSupported = errors.New("routific: not supported")
+)
+
+// APIError describes a failed API call. Use errors.As to retrieve it, and
+// errors.Is to match it against the sentinel errors.
+type APIError struct {
+	StatusCode int    // HTTP status; 0 if the failure is not an HTTP one
+	Message    string // error message decoded from Body, if any
+	Body       []byte // raw response body
+	URL        string // request URL
+	JobID      string // long-running job ID, if any
+	Err        error  // matching sentinel error, if any
+
+	// RetryAfter is the delay requested by the server's Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+
+	var b strings.Builder
+	b.WriteString("routific: ")
+
+	if e.JobID != "" {
+		fmt.Fprintf(&b, "job %s: ", e.JobID)
+	}
+
+	switch {
+	case e.StatusCode != 0:
+		fmt.Fprintf(&b, "status code %d from %s", e.StatusCode, e.URL)
+	case e.Err != nil:
+		b.WriteString(strings.TrimPrefix(e.Err.Error(), "routific: "))
+	default:
+		b.WriteString("request failed")
+	}
+
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+
+	return b.String()
+}
+
+// Unwrap returns the matching sentinel error, if any.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError builds the error for an unsuccessful HTTP response.
+func newAPIError(url string, statusCode int, body []byte) *APIError {
+
+	e := &APIError{
+		StatusCode: statusCode,
+		Message:    errorMessage(body),
+		Body:       body,
+		URL:        url,
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		e.Err = ErrUnauthorized
+	case http.StatusTooManyRequests:
+		e.Err = ErrRateLimited
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		e.Err = ErrInvalidInput
+	}
+
+	return e
+}
+
+// errorMessage extracts the message from a Routific error body, which is
+// either a JSON object with an "error" or "message" field, a JSON string, or
+// plain text.
+func errorMessage(body []byte) string {
+
+	var obj struct {
+		Error   interface{} `json:"error"`
+		Message interface{} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &obj); err == nil {
+		for _, m := range []interface{}{obj.Error, obj.Message} {
+			if s, ok := m.(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+
+	var s string
+	if err := json.Unmarshal(body, &s); err == nil {
+		return s
+	}
+
+	return strings.TrimSpace(string(body))
+}
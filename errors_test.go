@@ -0,0 +1,100 @@
+package routific_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// errors_test checks that failed calls are reported as APIError matching the
+// sentinel errors.
+// Test data is defined in setup_test.
+
+func TestAPIErrorStatus(t *testing.T) {
+
+	tests := []struct {
+		status   int
+		body     string
+		sentinel error
+		message  string
+	}{
+		{400, `{"error": "visit order_1 has no location"}`, r.ErrInvalidInput,
+			"visit order_1 has no location"},
+		{401, `{"message": "bad token"}`, r.ErrUnauthorized, "bad token"},
+		{429, "slow down\n", r.ErrRateLimited, "slow down"},
+		{500, "", nil, ""},
+	}
+
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+
+		_, err := r.NewClient("secret",
+			r.WithBaseURL(srv.URL),
+			r.WithRetryPolicy(r.NoRetry),
+		).VRP(vrpInput)
+		srv.Close()
+
+		var apiErr *r.APIError
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, tt.status, apiErr.StatusCode)
+		assert.Equal(t, tt.message, apiErr.Message)
+		assert.Equal(t, srv.URL+"/v1/vrp", apiErr.URL)
+		if tt.sentinel != nil {
+			assert.True(t, errors.Is(err, tt.sentinel))
+		}
+	}
+}
+
+func TestAPIErrorJob(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			switch req.Method {
+			case "POST":
+				w.WriteHeader(202)
+				w.Write([]byte(`{"job_id": "abc"}`))
+			default:
+				w.Write([]byte(`{"id": "abc", "status": "error", ` +
+					`"output": "no vehicles"}`))
+			}
+		}))
+	defer srv.Close()
+
+	_, err := r.NewClient("secret", r.WithBaseURL(srv.URL)).
+		LongVRP(vrpInput, 0, 1)
+
+	var apiErr *r.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.True(t, errors.Is(err, r.ErrJobFailed))
+	assert.Equal(t, "abc", apiErr.JobID)
+	assert.Equal(t, "no vehicles", apiErr.Message)
+	assert.Equal(t, "routific: job abc: job failed: no vehicles", err.Error())
+}
+
+func TestAPIErrorTimeout(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			switch req.Method {
+			case "POST":
+				w.WriteHeader(202)
+				w.Write([]byte(`{"job_id": "abc"}`))
+			default:
+				w.Write([]byte(`{"id": "abc", "status": "processing"}`))
+			}
+		}))
+	defer srv.Close()
+
+	_, err := r.NewClient("secret", r.WithBaseURL(srv.URL)).
+		LongPDP(pdpInput, 0, 2)
+
+	assert.True(t, errors.Is(err, r.ErrTimeout))
+}
@@ -0,0 +1,180 @@
+package routific
+
+import (
+	"strconv"
+
+	"github.com/slamethendry/routific/geojson"
+)
+
+// GeoJSON returns the visits and depots of the plan as points. Every
+// feature has a "kind" property: "visit" or "depot".
+func (p VRPlan) GeoJSON() geojson.FeatureCollection {
+
+	c := geojson.NewFeatureCollection()
+
+	for _, key := range sortedKeys(p.Visits) {
+		v := p.Visits[key]
+		props := locationProps("visit", key, v.Location)
+		timeProps(props, v.Start, v.End, v.Duration)
+		setProp(props, "load", v.Load, v.Load != nil)
+		setProp(props, "type", v.Type, v.Type != "")
+		setProp(props, "priority", v.Priority, v.Priority != "")
+		c.Add(point(v.Location, props))
+	}
+
+	c.Add(depotFeatures(p.Fleet)...)
+
+	return c
+}
+
+// GeoJSON returns the pickups, dropoffs and depots of the plan as points.
+// Every feature has a "kind" property: "pickup", "dropoff" or "depot".
+func (p PDPlan) GeoJSON() geojson.FeatureCollection {
+
+	c := geojson.NewFeatureCollection()
+
+	for _, key := range sortedKeys(p.Visits) {
+		order := p.Visits[key]
+		for _, part := range []struct {
+			kind string
+			d    Destination
+		}{{"pickup", order.PickUp}, {"dropoff", order.DropOff}} {
+			props := locationProps(part.kind, key, part.d.Location)
+			timeProps(props, part.d.Start, part.d.End, part.d.Duration)
+			setProp(props, "load", order.Load, order.Load != nil)
+			c.Add(point(part.d.Location, props))
+		}
+	}
+
+	c.Add(depotFeatures(p.Fleet)...)
+
+	return c
+}
+
+// GeoJSON returns the schedule of plan as features with a "kind" property:
+//   - "route": a line string for each vehicle, along its decoded polylines if
+//     the schedule has them, or else straight from stop to stop;
+//   - "visit", "depot" and "break": a point for each stop with a location,
+//     with its vehicle, sequence, and arrival, finish and lateness;
+//   - "unserved": a point for each location of an unserved visit, with the
+//     reason.
+//
+// The error matches ErrInvalidInput if a polyline is malformed.
+func (s Schedule) GeoJSON(plan Plan) (geojson.FeatureCollection, error) {
+
+	c := geojson.NewFeatureCollection()
+
+	for _, vehicle := range sortedKeys(s.Solution) {
+		stops := s.Solution[vehicle]
+		var line []geojson.Position
+		var points []geojson.Feature
+
+		for _, stop := range routeStops(plan, vehicle, stops) {
+			props := locationProps(stop.kind, stop.ID, stop.loc)
+			props["name"] = stop.Name
+			props["vehicle"] = vehicle
+			props["sequence"] = stop.seq
+			props["arrival_time"] = stop.ArrivalTime.String()
+			setProp(props, "finish_time", stop.FinishTime.String(), stop.HasFinish())
+			setProp(props, "type", stop.Type, stop.Type != "")
+			setProp(props, "late", true, stop.Late)
+			setProp(props, "late_by", stop.LateBy, stop.LateBy != 0)
+
+			points = append(points, point(stop.loc, props))
+			line = append(line, position(stop.loc))
+		}
+
+		source := "straight"
+		path, err := s.Geometry(vehicle)
+		if err != nil {
+			return geojson.FeatureCollection{}, err
+		}
+		if len(path) > 0 {
+			source = "polyline"
+			line = line[:0]
+			for _, loc := range path {
+				line = append(line, position(loc))
+			}
+		}
+
+		if len(line) > 1 {
+			c.Add(geojson.NewFeature(geojson.NewLineString(line),
+				map[string]interface{}{
+					"kind":     "route",
+					"vehicle":  vehicle,
+					"geometry": source,
+				}))
+		}
+		c.Add(points...)
+	}
+
+	for _, key := range sortedKeys(s.Unserved) {
+		for _, loc := range plan.orderLocations(key) {
+			props := locationProps("unserved", key, loc)
+			props["reason"] = s.Unserved[key]
+			c.Add(point(loc, props))
+		}
+	}
+
+	return c, nil
+}
+
+// depotFeatures returns the start and end locations of the fleet as points.
+func depotFeatures(fleet map[string]Vehicle) []geojson.Feature {
+
+	var features []geojson.Feature
+
+	for _, key := range sortedKeys(fleet) {
+		v := fleet[key]
+		for _, depot := range []struct {
+			role string
+			loc  Location
+		}{{"start", v.StartLocation}, {"end", v.EndLocation}} {
+			if depot.loc == (Location{}) {
+				continue
+			}
+			props := locationProps("depot", depot.loc.ID, depot.loc)
+			props["vehicle"] = key
+			props["role"] = depot.role
+			features = append(features, point(depot.loc, props))
+		}
+	}
+
+	return features
+}
+
+func locationProps(kind, id string, loc Location) map[string]interface{} {
+
+	props := map[string]interface{}{"kind": kind}
+	setProp(props, "id", id, id != "")
+	setProp(props, "name", loc.Name, loc.Name != "")
+
+	return props
+}
+
+func timeProps(props map[string]interface{}, start, end ClockTime, duration float32) {
+	setProp(props, "start", start.String(), start != 0)
+	setProp(props, "end", end.String(), end != 0)
+	setProp(props, "duration", duration, duration != 0)
+}
+
+func setProp(props map[string]interface{}, key string, value interface{}, ok bool) {
+	if ok {
+		props[key] = value
+	}
+}
+
+func point(loc Location, props map[string]interface{}) geojson.Feature {
+	return geojson.NewFeature(geojson.NewPoint(position(loc)), props)
+}
+
+// position converts loc without the noise of widening float32 to float64,
+// e.g. 49.255363 rather than 49.25536346435547.
+func position(loc Location) geojson.Position {
+	return geojson.Position{widen(loc.Longitude), widen(loc.Latitude)}
+}
+
+func widen(f float32) float64 {
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+	return v
+}
@@ -0,0 +1,112 @@
+package routific_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/geojson"
+	"github.com/stretchr/testify/assert"
+)
+
+// geo_test checks the GeoJSON export of plans and schedules.
+// Test data is defined in setup_test.
+
+// kinds counts the features of c by their "kind" property.
+func kinds(c geojson.FeatureCollection) map[string]int {
+	n := map[string]int{}
+	for _, f := range c.Features {
+		n[f.Properties["kind"].(string)]++
+	}
+	return n
+}
+
+func TestPlanGeoJSON(t *testing.T) {
+
+	c := vrpInput.GeoJSON()
+	assert.Equal(t, "FeatureCollection", c.Type)
+	assert.Equal(t, map[string]int{"visit": 3, "depot": 2}, kinds(c))
+
+	visit := c.Features[0]
+	assert.Equal(t, geojson.NewPoint(geojson.Position{-123.11631, 49.227108}),
+		visit.Geometry)
+	assert.Equal(t, map[string]interface{}{
+		"kind": "visit",
+		"id":   "order_1",
+		"name": "6800 Cambie",
+	}, visit.Properties)
+
+	c = pdpInput.GeoJSON()
+	assert.Equal(t, map[string]int{"pickup": 2, "dropoff": 2, "depot": 4}, kinds(c))
+	pickup := c.Features[0].Properties
+	assert.Equal(t, "pickup", pickup["kind"])
+	assert.Equal(t, "09:00", pickup["start"])
+	assert.Equal(t, "12:00", pickup["end"])
+	assert.Equal(t, float32(10), pickup["duration"])
+
+	b, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b),
+		`"geometry":{"type":"Point","coordinates":[-123.15324,49.247463]}`)
+	assert.Contains(t, string(b), `"load":1`)
+}
+
+func TestScheduleGeoJSON(t *testing.T) {
+
+	output := vrpOutput
+	output.Solution = map[string]r.Stops{"vehicle_1": {
+		vrpDepot,
+		{ID: "order_3", Name: "800 Robson", ArrivalTime: r.Clock(8, 10)},
+		{ID: "order_2", Name: "3780 Arbutus", ArrivalTime: r.Clock(8, 30),
+			Late: true, LateBy: 5},
+		vrpDepot,
+	}}
+	output.Unserved = map[string]string{"order_1": "Cannot be visited"}
+
+	c, err := output.GeoJSON(vrpInput)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{
+		"route": 1, "depot": 2, "visit": 2, "unserved": 1}, kinds(c))
+
+	route := c.Features[0]
+	assert.Equal(t, "straight", route.Properties["geometry"])
+	assert.Equal(t, []geojson.Position{
+		{-123.08733, 49.255363},
+		{-123.121185, 49.28192},
+		{-123.15324, 49.247463},
+		{-123.08733, 49.255363},
+	}, route.Geometry.LineString)
+
+	late := c.Features[3].Properties
+	assert.Equal(t, "order_2", late["id"])
+	assert.Equal(t, 2, late["sequence"])
+	assert.Equal(t, "08:30", late["arrival_time"])
+	assert.Equal(t, true, late["late"])
+	assert.Equal(t, float32(5), late["late_by"])
+
+	unserved := c.Features[5]
+	assert.Equal(t, "Cannot be visited", unserved.Properties["reason"])
+	assert.Equal(t, geojson.Position{-123.11631, 49.227108}, unserved.Geometry.Point)
+
+	// Decoded polylines replace the straight lines
+	output.Polylines = map[string]r.Polylines{"vehicle_1": {"_p~iF~ps|U_ulLnnqC"}}
+	c, err = output.GeoJSON(vrpInput)
+	assert.Nil(t, err)
+	assert.Equal(t, "polyline", c.Features[0].Properties["geometry"])
+	assert.Equal(t, []geojson.Position{{-120.2, 38.5}, {-120.95, 40.7}},
+		c.Features[0].Geometry.LineString)
+}
+
+func TestPDPScheduleGeoJSON(t *testing.T) {
+
+	c, err := pdpOutput.GeoJSON(pdpInput)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"route": 2, "depot": 4, "visit": 4}, kinds(c))
+
+	// The dropoff of order_1 is at Cambie, its pickup at Arbutus
+	for _, f := range c.Features {
+		if f.Properties["id"] == "order_1" && f.Properties["type"] == "dropoff" {
+			assert.Equal(t, geojson.Position{-123.11631, 49.227108}, f.Geometry.Point)
+		}
+	}
+}
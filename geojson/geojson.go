@@ -0,0 +1,118 @@
+// Package geojson defines the GeoJSON types that package routific exports
+// plans and schedules as: feature collections of points and line strings.
+// See [RFC 7946]: https://datatracker.ietf.org/doc/html/rfc7946
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Geometry types.
+const (
+	TypePoint      = "Point"
+	TypeLineString = "LineString"
+)
+
+// Position is a longitude and a latitude, in this order.
+type Position [2]float64
+
+// Geometry is a Point or a LineString.
+type Geometry struct {
+	Type       string
+	Point      Position   // if Type is TypePoint
+	LineString []Position // if Type is TypeLineString
+}
+
+// NewPoint returns a Point geometry.
+func NewPoint(p Position) *Geometry {
+	return &Geometry{Type: TypePoint, Point: p}
+}
+
+// NewLineString returns a LineString geometry.
+func NewLineString(line []Position) *Geometry {
+	return &Geometry{Type: TypeLineString, LineString: line}
+}
+
+// geometry is the JSON form of a Geometry.
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// MarshalJSON writes the coordinates of g according to its type.
+func (g Geometry) MarshalJSON() ([]byte, error) {
+
+	var coords interface{}
+	switch g.Type {
+	case TypePoint:
+		coords = g.Point
+	case TypeLineString:
+		line := g.LineString
+		if line == nil {
+			line = []Position{}
+		}
+		coords = line
+	default:
+		return nil, fmt.Errorf("geojson: unsupported geometry type %q", g.Type)
+	}
+
+	b, err := json.Marshal(coords)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(geometry{g.Type, b})
+}
+
+// UnmarshalJSON reads a Point or a LineString.
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+
+	var v geometry
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*g = Geometry{Type: v.Type}
+	switch v.Type {
+	case TypePoint:
+		return json.Unmarshal(v.Coordinates, &g.Point)
+	case TypeLineString:
+		return json.Unmarshal(v.Coordinates, &g.LineString)
+	}
+
+	return fmt.Errorf("geojson: unsupported geometry type %q", v.Type)
+}
+
+// Feature is a geometry with properties.
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// NewFeature returns a Feature of g with the given properties.
+func NewFeature(g *Geometry, properties map[string]interface{}) Feature {
+	return Feature{Type: "Feature", Geometry: g, Properties: properties}
+}
+
+// FeatureCollection is a list of features.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// NewFeatureCollection returns a FeatureCollection of the features.
+func NewFeatureCollection(features ...Feature) FeatureCollection {
+
+	if features == nil {
+		features = []Feature{}
+	}
+
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// Add appends features to c.
+func (c *FeatureCollection) Add(features ...Feature) {
+	c.Features = append(c.Features, features...)
+}
@@ -0,0 +1,55 @@
+package geojson_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/slamethendry/routific/geojson"
+	"github.com/stretchr/testify/assert"
+)
+
+// geojson_test checks the JSON encoding of the GeoJSON types.
+
+func TestFeatureCollectionJSON(t *testing.T) {
+
+	c := geojson.NewFeatureCollection(
+		geojson.NewFeature(geojson.NewPoint(geojson.Position{-123.1, 49.2}),
+			map[string]interface{}{"kind": "depot"}),
+		geojson.NewFeature(geojson.NewLineString([]geojson.Position{
+			{-123.1, 49.2}, {-123.2, 49.3},
+		}), map[string]interface{}{"kind": "route"}),
+	)
+
+	b, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature",
+			 "geometry": {"type": "Point", "coordinates": [-123.1, 49.2]},
+			 "properties": {"kind": "depot"}},
+			{"type": "Feature",
+			 "geometry": {"type": "LineString",
+			              "coordinates": [[-123.1, 49.2], [-123.2, 49.3]]},
+			 "properties": {"kind": "route"}}
+		]
+	}`, string(b))
+
+	var again geojson.FeatureCollection
+	assert.Nil(t, json.Unmarshal(b, &again))
+	assert.Equal(t, c, again)
+}
+
+func TestEmptyAndInvalid(t *testing.T) {
+
+	b, err := json.Marshal(geojson.NewFeatureCollection())
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"type": "FeatureCollection", "features": []}`, string(b))
+
+	_, err = json.Marshal(geojson.Geometry{Type: "Polygon"})
+	assert.NotNil(t, err)
+
+	var g geojson.Geometry
+	err = json.Unmarshal([]byte(`{"type": "Polygon", "coordinates": []}`), &g)
+	assert.NotNil(t, err)
+}
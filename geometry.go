@@ -0,0 +1,34 @@
+package routific
+
+import (
+	"fmt"
+
+	"github.com/slamethendry/routific/internal/polyenc"
+)
+
+// Geometry returns the path driven by the vehicle, decoded from its
+// Polylines, with the legs joined. It is nil if the schedule has no
+// polylines for the vehicle, e.g. because Options.Polylines was not set.
+// The error matches ErrInvalidInput if a polyline is malformed.
+func (s Schedule) Geometry(vehicleID string) ([]Location, error) {
+
+	var path []Location
+
+	for i, line := range s.Polylines[vehicleID] {
+		start := len(path)
+		err := polyenc.Decode(line, 5, func(lat, lng float64) {
+			loc := Location{Latitude: float32(lat), Longitude: float32(lng)}
+			// Legs share their end points
+			if len(path) == start && start > 0 && path[start-1] == loc {
+				return
+			}
+			path = append(path, loc)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: polyline %d of vehicle %s: %v",
+				ErrInvalidInput, i, vehicleID, err)
+		}
+	}
+
+	return path, nil
+}
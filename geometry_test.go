@@ -0,0 +1,40 @@
+package routific_test
+
+import (
+	"errors"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// geometry_test checks decoding the polylines of a schedule into paths.
+
+func TestGeometry(t *testing.T) {
+
+	s := r.Schedule{Polylines: map[string]r.Polylines{
+		// Two legs meeting at (40.7, -120.95)
+		"vehicle_1": {"_p~iF~ps|U_ulLnnqC", "_flwFn`faV_mqNvxq`@"},
+		"vehicle_2": {"_p~iF~ps|U_ulLnnqC_mqNvxq`@"},
+		"broken":    {"_p~iF~ps|"},
+	}}
+
+	path, err := s.Geometry("vehicle_1")
+	assert.Nil(t, err)
+	assert.Equal(t, []r.Location{
+		{Latitude: 38.5, Longitude: -120.2},
+		{Latitude: 40.7, Longitude: -120.95},
+		{Latitude: 43.252, Longitude: -126.453},
+	}, path)
+
+	whole, err := s.Geometry("vehicle_2")
+	assert.Nil(t, err)
+	assert.Equal(t, path, whole)
+
+	path, err = s.Geometry("vehicle_3")
+	assert.Nil(t, err)
+	assert.Nil(t, path)
+
+	_, err = s.Geometry("broken")
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+}
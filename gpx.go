@@ -0,0 +1,130 @@
+package routific
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// gpxDoc is a GPX 1.1 document.
+// See [GPX]: https://www.topografix.com/GPX/1/1/
+type gpxDoc struct {
+	XMLName   xml.Name   `xml:"gpx"`
+	Version   string     `xml:"version,attr"`
+	Creator   string     `xml:"creator,attr"`
+	Namespace string     `xml:"xmlns,attr"`
+	Name      string     `xml:"metadata>name"`
+	Waypoints []gpxPoint `xml:"wpt"`
+	Route     gpxRoute   `xml:"rte"`
+	Track     *gpxTrack  `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Lat  float64    `xml:"lat,attr"`
+	Lon  float64    `xml:"lon,attr"`
+	Time *time.Time `xml:"time,omitempty"`
+	Name string     `xml:"name,omitempty"`
+	Desc string     `xml:"desc,omitempty"`
+	Type string     `xml:"type,omitempty"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name   string     `xml:"name"`
+	Points []gpxPoint `xml:"trkseg>trkpt"`
+}
+
+// GPX returns the route of the vehicle as a GPX document for GPS devices:
+// its stops as waypoints and as a route, in order and named by Stop.Name,
+// from the start depot to the end depot. The arrival and finish times are
+// in the descriptions. If the schedule has polylines for the vehicle, the
+// path along the roads is added as a track. The error matches
+// ErrInvalidInput if the schedule has no route for the vehicle.
+func (s Schedule) GPX(plan Plan, vehicleID string) ([]byte, error) {
+	return s.gpx(plan, vehicleID, nil)
+}
+
+// GPX is like Schedule.GPX, with the arrival times of the waypoints as
+// timestamps.
+func (t TimedSchedule) GPX(plan Plan, vehicleID string) ([]byte, error) {
+	return t.Schedule.gpx(plan, vehicleID, t.Routes[vehicleID])
+}
+
+// gpx writes the GPX document, with the arrival times of timed if given.
+func (s Schedule) gpx(plan Plan, vehicleID string, timed []TimedStop) ([]byte, error) {
+
+	stops, ok := s.Solution[vehicleID]
+	if !ok {
+		return nil, fmt.Errorf("%w: no route for vehicle %s", ErrInvalidInput, vehicleID)
+	}
+
+	doc := gpxDoc{
+		Version:   "1.1",
+		Creator:   "routific-go",
+		Namespace: "http://www.topografix.com/GPX/1/1",
+		Name:      vehicleID,
+		Route:     gpxRoute{Name: vehicleID},
+	}
+
+	for _, stop := range routeStops(plan, vehicleID, stops) {
+		p := gpxPoint{
+			Lat:  widen(stop.loc.Latitude),
+			Lon:  widen(stop.loc.Longitude),
+			Name: stop.label(),
+			Desc: stop.times(),
+			Type: stop.kind,
+		}
+		if stop.seq < len(timed) {
+			at := timed[stop.seq].Arrival.UTC()
+			p.Time = &at
+		}
+		doc.Waypoints = append(doc.Waypoints, p)
+		doc.Route.Points = append(doc.Route.Points, p)
+	}
+
+	path, err := s.Geometry(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) > 0 {
+		doc.Track = &gpxTrack{Name: vehicleID}
+		for _, loc := range path {
+			doc.Track.Points = append(doc.Track.Points, gpxPoint{
+				Lat: widen(loc.Latitude),
+				Lon: widen(loc.Longitude),
+			})
+		}
+	}
+
+	return marshalXML(doc)
+}
+
+// times describes the arrival and finish of the stop, e.g.
+// "Arrival 09:05, finish 09:15".
+func (s routeStop) times() string {
+
+	desc := "Arrival " + s.ArrivalTime.String()
+	if s.HasFinish() {
+		desc += ", finish " + s.FinishTime.String()
+	}
+	if s.Late {
+		desc += fmt.Sprintf(", late by %g min", s.LateBy)
+	}
+
+	return desc
+}
+
+// marshalXML returns the indented XML document of v with a declaration.
+func marshalXML(v interface{}) ([]byte, error) {
+
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
@@ -0,0 +1,86 @@
+package routific_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// gpx_test checks the GPX export of routes.
+// Test data is defined in setup_test.
+
+var gpxSchedule = r.Schedule{Solution: map[string]r.Stops{"vehicle_1": {
+	{ID: "depot", Name: "800 Kingsway", ArrivalTime: r.Clock(8, 0)},
+	{ID: "order_3", Name: "800 Robson", ArrivalTime: r.Clock(8, 20),
+		FinishTime: r.Clock(8, 30)},
+	{ID: "lunch", ArrivalTime: r.Clock(8, 30), FinishTime: r.Clock(9, 0), Break: true},
+	{ID: "depot", Name: "800 Kingsway", ArrivalTime: r.Clock(9, 20)},
+}}}
+
+func TestGPX(t *testing.T) {
+
+	b, err := gpxSchedule.GPX(vrpInput, "vehicle_1")
+	assert.Nil(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="routific-go" xmlns="http://www.topografix.com/GPX/1/1">
+  <metadata>
+    <name>vehicle_1</name>
+  </metadata>
+  <wpt lat="49.255363" lon="-123.08733">
+    <name>800 Kingsway</name>
+    <desc>Arrival 08:00</desc>
+    <type>depot</type>
+  </wpt>
+  <wpt lat="49.28192" lon="-123.121185">
+    <name>800 Robson</name>
+    <desc>Arrival 08:20, finish 08:30</desc>
+    <type>visit</type>
+  </wpt>
+  <wpt lat="49.255363" lon="-123.08733">
+    <name>800 Kingsway</name>
+    <desc>Arrival 09:20</desc>
+    <type>depot</type>
+  </wpt>
+  <rte>
+    <name>vehicle_1</name>
+    <rtept lat="49.255363" lon="-123.08733">
+      <name>800 Kingsway</name>
+      <desc>Arrival 08:00</desc>
+      <type>depot</type>
+    </rtept>
+    <rtept lat="49.28192" lon="-123.121185">
+      <name>800 Robson</name>
+      <desc>Arrival 08:20, finish 08:30</desc>
+      <type>visit</type>
+    </rtept>
+    <rtept lat="49.255363" lon="-123.08733">
+      <name>800 Kingsway</name>
+      <desc>Arrival 09:20</desc>
+      <type>depot</type>
+    </rtept>
+  </rte>
+</gpx>
+`, string(b))
+
+	_, err = gpxSchedule.GPX(vrpInput, "vehicle_9")
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+}
+
+func TestTimedGPX(t *testing.T) {
+
+	loc := time.FixedZone("PDT", -7*60*60)
+	timed := gpxSchedule.Materialize(time.Date(2022, 6, 1, 0, 0, 0, 0, loc), loc)
+	timed.Polylines = map[string]r.Polylines{"vehicle_1": {"_p~iF~ps|U_ulLnnqC"}}
+
+	b, err := timed.GPX(vrpInput, "vehicle_1")
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `<time>2022-06-01T15:00:00Z</time>`)
+	assert.Contains(t, string(b), `<time>2022-06-01T16:20:00Z</time>`)
+	assert.Contains(t, string(b), `<trkseg>
+      <trkpt lat="38.5" lon="-120.2"></trkpt>
+      <trkpt lat="40.7" lon="-120.95"></trkpt>
+    </trkseg>`)
+}
@@ -1,69 +1,120 @@
 package routific
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
-	"strings"
 	"time"
 )
 
 // post performs http POST, specifying auth token and JSON type
-func post(visits interface{}, url string, token string) ([]byte, error) {
+func (c *Client) post(ctx context.Context, visits interface{}, path string) ([]byte, error) {
 
-	v, err := json.Marshal(visits)
-	if err != nil {
-		return []byte{}, err
+	if c.validate {
+		if p, ok := visits.(interface{ Validate() error }); ok {
+			if err := p.Validate(); err != nil {
+				return []byte{}, err
+			}
+		}
 	}
 
-	client := &http.Client{
-		Timeout: 3 * time.Second,
-	}
-	req, err := http.NewRequest("POST", url, strings.NewReader(string(v)))
+	v, err := json.Marshal(visits)
 	if err != nil {
 		return []byte{}, err
 	}
 
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "bearer "+token)
+	return c.do(ctx, "POST", path, v)
+}
 
-	res, err := client.Do(req)
-	if err != nil {
-		return []byte{}, err
-	}
+// get performs http GET, specifying auth token
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
 
-	if res.StatusCode == 200 || res.StatusCode == 202 {
-		return ioutil.ReadAll(res.Body)
-	}
+	return c.do(ctx, "GET", path, nil)
+}
+
+// do sends a request, retrying it as the retry policy allows, and returns
+// the body of a 200 or 202 response. A POST is not retried if it may have
+// been processed, unless c.retryPosts is set.
+// The request is aborted when ctx is done.
+func (c *Client) do(
+	ctx context.Context,
+	method string,
+	path string,
+	body []byte,
+) ([]byte, error) {
+
+	for attempt := 1; ; attempt++ {
+
+		data, err := c.send(ctx, method, path, body)
+		if err == nil || ctx.Err() != nil {
+			return data, err
+		}
 
-	e := fmt.Sprintf("Status Code %d", res.StatusCode)
-	return []byte{}, errors.New(e)
+		if method == "POST" && !c.retryPosts && !notSent(err) {
+			return data, err
+		}
+
+		wait, ok := c.retry.Retry(attempt, err)
+		if !ok {
+			return data, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return []byte{}, fmt.Errorf("retrying %s: %w", path, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
 }
 
-// get performs http GET, specifying auth token
-func get(url string, token string) ([]byte, error) {
+// send sends a single request and returns the body of a 200 or 202 response.
+func (c *Client) send(
+	ctx context.Context,
+	method string,
+	path string,
+	body []byte,
+) ([]byte, error) {
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
 
-	client := &http.Client{
-		Timeout: 3 * time.Second,
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
 	}
-	req, err := http.NewRequest("GET", url, nil)
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path), reader)
 	if err != nil {
 		return []byte{}, err
 	}
 
-	req.Header.Add("Authorization", "bearer "+token)
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	req.Header.Add("Authorization", "bearer "+c.token)
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
 
-	res, err := client.Do(req)
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return []byte{}, err
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode == 200 || res.StatusCode == 202 {
 		return ioutil.ReadAll(res.Body)
 	}
 
-	e := fmt.Sprintf("Status Code %d", res.StatusCode)
-	return []byte{}, errors.New(e)
+	msg, _ := ioutil.ReadAll(res.Body)
+	e := newAPIError(req.URL.String(), res.StatusCode, msg)
+	e.RetryAfter = retryAfter(res.Header.Get("Retry-After"), time.Now())
+	return []byte{}, e
 }
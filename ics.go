@@ -0,0 +1,196 @@
+package routific
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsTime is the format of UTC times in iCalendar.
+const icsTime = "20060102T150405Z"
+
+// ICS returns the day of the vehicle as an iCalendar (RFC 5545) calendar for
+// the driver's phone: an event for each stop from its arrival to its finish,
+// with the location name and coordinates and the notes of the visit, and
+// events for the start and end of the shift. Times are placed on date in tz,
+// as by Materialize, which is UTC if nil.
+//
+// Event UIDs depend on the vehicle, the date and the stops, but not on the
+// times, so that importing the calendar of a re-optimised schedule updates
+// the events of the previous one. The error matches ErrInvalidInput if the
+// schedule has no route for the vehicle.
+func (s Schedule) ICS(
+	plan Plan,
+	vehicleID string,
+	date time.Time,
+	tz *time.Location,
+) ([]byte, error) {
+
+	stops, ok := s.Solution[vehicleID]
+	if !ok {
+		return nil, fmt.Errorf("%w: no route for vehicle %s", ErrInvalidInput, vehicleID)
+	}
+
+	timed := s.Materialize(date, tz)
+	route := timed.Routes[vehicleID]
+	day := timed.Date.Format("2006-01-02")
+	stamp := time.Now().UTC().Format(icsTime)
+
+	var w icsWriter
+	w.prop("BEGIN", "VCALENDAR")
+	w.prop("VERSION", "2.0")
+	w.prop("PRODID", "-//routific-go//EN")
+	w.prop("CALSCALE", "GREGORIAN")
+	w.prop("METHOD", "PUBLISH")
+	w.text("X-WR-CALNAME", vehicleID+" "+day)
+	w.prop("X-WR-TIMEZONE", timed.Location().String())
+
+	// event writes an event, calling more for properties beyond the times
+	// and summary
+	event := func(uid, summary string, start, end time.Time, more func()) {
+		w.prop("BEGIN", "VEVENT")
+		w.prop("UID", icsUID(day, vehicleID, uid))
+		w.prop("DTSTAMP", stamp)
+		w.prop("DTSTART", start.UTC().Format(icsTime))
+		if end.After(start) {
+			w.prop("DTEND", end.UTC().Format(icsTime))
+		}
+		w.text("SUMMARY", summary)
+		if more != nil {
+			more()
+		}
+		w.prop("END", "VEVENT")
+	}
+
+	vehicle := plan.Vehicles()[vehicleID]
+	shiftStart, shiftEnd := timed.shift(vehicle, route)
+
+	event("shift-start", "Shift start", shiftStart, shiftStart, nil)
+
+	seen := map[string]int{}
+	located := map[int]routeStop{}
+	for _, stop := range routeStops(plan, vehicleID, stops) {
+		located[stop.seq] = stop
+	}
+
+	for i, stop := range route {
+		key := stop.ID + "/" + stop.Type
+		seen[key]++
+
+		rs, ok := located[i]
+		summary := stop.Name
+		if summary == "" {
+			summary = stop.ID
+		}
+		switch {
+		case stop.Break:
+			summary = "Break: " + summary
+		case ok && rs.kind == "depot":
+			summary = "Depot: " + summary
+		case stop.Type == "pickup":
+			summary = "Pickup: " + summary
+		case stop.Type == "dropoff":
+			summary = "Dropoff: " + summary
+		}
+
+		uid := fmt.Sprintf("stop/%s/%d", key, seen[key])
+		event(uid, summary, stop.Arrival, stop.Finish, func() {
+			if ok {
+				if rs.loc.Name != "" {
+					w.text("LOCATION", rs.loc.Name)
+				}
+				w.prop("GEO", fmt.Sprintf("%s;%s",
+					formatFloat32(rs.loc.Latitude), formatFloat32(rs.loc.Longitude)))
+			}
+			if notes := plan.visitNotes(stop.Stop); notes != "" && !stop.Break {
+				w.text("DESCRIPTION", notes)
+			}
+		})
+	}
+
+	event("shift-end", "Shift end", shiftEnd, shiftEnd, nil)
+
+	w.prop("END", "VCALENDAR")
+
+	return w.b.Bytes(), nil
+}
+
+// shift returns the start and end of the vehicle's shift on the date of the
+// schedule, defaulting to the first and last stops of its route.
+func (t TimedSchedule) shift(v Vehicle, route []TimedStop) (time.Time, time.Time) {
+
+	var start, end time.Time
+	if len(route) > 0 {
+		start, end = route[0].Arrival, route[len(route)-1].Arrival
+		if f := route[len(route)-1].Finish; f.After(end) {
+			end = f
+		}
+	}
+
+	if v.ShiftStart != 0 {
+		start = v.ShiftStart.On(t.Date, t.Location())
+	}
+	if v.ShiftEnd != 0 {
+		end = v.ShiftEnd.On(t.Date, t.Location())
+		if v.ShiftEnd < v.ShiftStart {
+			end = end.AddDate(0, 0, 1) // overnight shift
+		}
+	}
+
+	return start, end
+}
+
+// icsUID returns a stable UID for an event of the vehicle on the day.
+func icsUID(day, vehicleID, event string) string {
+	sum := sha256.Sum256([]byte(day + "\x00" + vehicleID + "\x00" + event))
+	return hex.EncodeToString(sum[:12]) + "@routific-go"
+}
+
+// icsWriter writes iCalendar content lines.
+type icsWriter struct {
+	b bytes.Buffer
+}
+
+// text writes a property with a TEXT value, escaping it.
+func (w *icsWriter) text(name, value string) {
+
+	value = strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+
+	w.prop(name, value)
+}
+
+// prop writes a content line, folded into lines of at most 75 octets
+// without splitting characters, and ended by CRLF.
+func (w *icsWriter) prop(name, value string) {
+
+	line := name + ":" + value
+	limit := 75
+
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.b.WriteString(line[:cut])
+		w.b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // after the leading space
+	}
+
+	w.b.WriteString(line)
+	w.b.WriteString("\r\n")
+}
+
+func formatFloat32(f float32) string {
+	return fmt.Sprint(widen(f))
+}
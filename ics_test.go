@@ -0,0 +1,143 @@
+package routific_test
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// ics_test checks the iCalendar export of a driver's day. The schedule is
+// defined in gpx_test and the plan in setup_test.
+
+// unfold returns the content lines of an iCalendar file, without the
+// DTSTAMP lines, which change with every export.
+func unfold(ics string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(ics, "\r\n ", ""), "\r\n") {
+		if line != "" && !strings.HasPrefix(line, "DTSTAMP:") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestICS(t *testing.T) {
+
+	plan := vrpInput
+	plan.Visits = map[string]r.Visit{}
+	for key, visit := range vrpInput.Visits {
+		plan.Visits[key] = visit
+	}
+	robsonVisit := plan.Visits["order_3"]
+	robsonVisit.Notes = "Ring twice; parcel, fragile.\nUse the back door, the front door is for residents only."
+	plan.Visits["order_3"] = robsonVisit
+
+	vehicle := plan.Fleet["vehicle_1"]
+	vehicle.ShiftStart = r.Clock(7, 45)
+	plan.Fleet = map[string]r.Vehicle{"vehicle_1": vehicle}
+
+	tz := time.FixedZone("PDT", -7*60*60)
+	date := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	b, err := gpxSchedule.ICS(plan, "vehicle_1", date, tz)
+	assert.Nil(t, err)
+
+	ics := string(b)
+	assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	assert.NotContains(t, strings.ReplaceAll(ics, "\r\n", ""), "\n")
+	for _, line := range strings.Split(ics, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+	assert.Regexp(t, regexp.MustCompile(`DTSTAMP:\d{8}T\d{6}Z\r\n`), ics)
+
+	uid := regexp.MustCompile(`^UID:[0-9a-f]{24}@routific-go$`)
+	var uids []string
+	var lines []string
+	for _, line := range unfold(ics) {
+		if strings.HasPrefix(line, "UID:") {
+			assert.Regexp(t, uid, line)
+			uids = append(uids, line)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	assert.Len(t, uids, 6)
+
+	assert.Equal(t, []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//routific-go//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:vehicle_1 2022-06-01",
+		"X-WR-TIMEZONE:PDT",
+		"BEGIN:VEVENT",
+		"DTSTART:20220601T144500Z",
+		"SUMMARY:Shift start",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20220601T150000Z",
+		`SUMMARY:Depot: 800 Kingsway`,
+		"LOCATION:800 Kingsway",
+		"GEO:49.255363;-123.08733",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20220601T152000Z",
+		"DTEND:20220601T153000Z",
+		"SUMMARY:800 Robson",
+		"LOCATION:800 Robson",
+		"GEO:49.28192;-123.121185",
+		`DESCRIPTION:Ring twice\; parcel\, fragile.\nUse the back door\, the front door is for residents only.`,
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20220601T153000Z",
+		"DTEND:20220601T160000Z",
+		"SUMMARY:Break: lunch",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20220601T162000Z",
+		`SUMMARY:Depot: 800 Kingsway`,
+		"LOCATION:800 Kingsway",
+		"GEO:49.255363;-123.08733",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20220601T162000Z",
+		"SUMMARY:Shift end",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, lines)
+
+	// A re-optimised schedule keeps the UIDs of the same stops
+	later := r.Schedule{Solution: map[string]r.Stops{"vehicle_1": {}}}
+	for _, stop := range gpxSchedule.Solution["vehicle_1"] {
+		stop.ArrivalTime = stop.ArrivalTime.Add(15 * time.Minute)
+		if stop.FinishTime != 0 {
+			stop.FinishTime = stop.FinishTime.Add(15 * time.Minute)
+		}
+		later.Solution["vehicle_1"] = append(later.Solution["vehicle_1"], stop)
+	}
+	b, err = later.ICS(plan, "vehicle_1", date, tz)
+	assert.Nil(t, err)
+	var again []string
+	for _, line := range unfold(string(b)) {
+		if strings.HasPrefix(line, "UID:") {
+			again = append(again, line)
+		}
+	}
+	assert.Equal(t, uids, again)
+	assert.Contains(t, string(b), "DTSTART:20220601T153500Z")
+
+	_, err = gpxSchedule.ICS(plan, "vehicle_9", date, tz)
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+
+	// Without a time zone, times are in UTC
+	b, err = gpxSchedule.ICS(plan, "vehicle_1", date, nil)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), "X-WR-TIMEZONE:UTC\r\n")
+	assert.Contains(t, string(b), "DTSTART:20220601T074500Z\r\n")
+}
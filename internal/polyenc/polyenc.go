@@ -0,0 +1,99 @@
+// Package polyenc implements the encoded polyline algorithm, shared by
+// package routific and package polyline.
+// See [Format]: https://developers.google.com/maps/documentation/utilities/polylinealgorithm
+package polyenc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Decode decodes the polyline s with the given number of decimals, calling
+// add for every point in order.
+func Decode(s string, precision int, add func(lat, lng float64)) error {
+
+	factor := math.Pow10(precision)
+	var lat, lng int64
+
+	for i := 0; i < len(s); {
+		dLat, n, err := decodeValue(s, i)
+		if err != nil {
+			return err
+		}
+		i = n
+
+		dLng, n, err := decodeValue(s, i)
+		if err != nil {
+			return err
+		}
+		i = n
+
+		lat += dLat
+		lng += dLng
+		add(float64(lat)/factor, float64(lng)/factor)
+	}
+
+	return nil
+}
+
+// decodeValue decodes the signed value starting at byte i of s, and returns
+// it with the index of the next value.
+func decodeValue(s string, i int) (int64, int, error) {
+
+	var result int64
+	for shift := uint(0); ; shift += 5 {
+		if i >= len(s) {
+			return 0, i, fmt.Errorf("polyline truncated at byte %d", i)
+		}
+		b := int64(s[i]) - 63
+		if b < 0 || b > 63 || shift > 60 {
+			return 0, i, fmt.Errorf("invalid polyline byte %q at %d", s[i], i)
+		}
+		i++
+		result |= (b & 0x1f) << shift
+		if b < 0x20 {
+			break
+		}
+	}
+
+	if result&1 != 0 {
+		return ^(result >> 1), i, nil
+	}
+
+	return result >> 1, i, nil
+}
+
+// Encode encodes n points with the given number of decimals, where at
+// returns the point i.
+func Encode(n, precision int, at func(i int) (lat, lng float64)) string {
+
+	factor := math.Pow10(precision)
+	var b strings.Builder
+	var prevLat, prevLng int64
+
+	for i := 0; i < n; i++ {
+		lat, lng := at(i)
+		iLat := int64(math.Round(lat * factor))
+		iLng := int64(math.Round(lng * factor))
+		encodeValue(&b, iLat-prevLat)
+		encodeValue(&b, iLng-prevLng)
+		prevLat, prevLng = iLat, iLng
+	}
+
+	return b.String()
+}
+
+func encodeValue(b *strings.Builder, v int64) {
+
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+
+	for u >= 0x20 {
+		b.WriteByte(byte((0x20 | (u & 0x1f)) + 63))
+		u >>= 5
+	}
+	b.WriteByte(byte(u + 63))
+}
@@ -0,0 +1,180 @@
+package routific
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JobState is the state of a long-running job as reported by Routific.
+type JobState string
+
+// States of a long-running job.
+const (
+	JobPending    JobState = "pending"
+	JobProcessing JobState = "processing"
+	JobFinished   JobState = "finished"
+	JobError      JobState = "error"
+)
+
+// Job is a handle on a submitted long-running job. It can be persisted, e.g.
+// as JSON, so that any process can check on the job and fetch its result.
+type Job struct {
+	ID          string    `json:"id"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	Endpoint    string    `json:"endpoint"` // e.g. "/v1/vrp-long"
+}
+
+// JobStatus describes the progress of a long-running job.
+// See [Jobs]: https://docs.routific.com/reference/jobs
+type JobStatus struct {
+	ID       string   `json:"id"`
+	Status   JobState `json:"status"`
+	Progress string   `json:"progress,omitempty"` // e.g. "Optimizing", or "42" if a number
+	Message  string   `json:"message,omitempty"`  // if Status is JobError
+}
+
+// jobResponse is the body of GET /jobs/{id}.
+type jobResponse struct {
+	ID       string          `json:"id"`
+	Status   JobState        `json:"status"`
+	Progress json.RawMessage `json:"progress,omitempty"`
+	Output   json.RawMessage `json:"output,omitempty"`
+}
+
+// SubmitVRPJob submits a long-running vehicle routing job and returns
+// without waiting for it.
+func (c *Client) SubmitVRPJob(ctx context.Context, visits VRPlan) (Job, error) {
+	return c.submitJob(ctx, visits, vrpLongPath)
+}
+
+// SubmitPDPJob submits a long-running pickup-and-delivery job and returns
+// without waiting for it.
+func (c *Client) SubmitPDPJob(ctx context.Context, visits PDPlan) (Job, error) {
+	return c.submitJob(ctx, visits, pdpLongPath)
+}
+
+// JobStatus returns the current status of the job with the given ID.
+func (c *Client) JobStatus(ctx context.Context, id string) (JobStatus, error) {
+
+	res, err := c.fetchJob(ctx, id)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	status := JobStatus{
+		ID:       id,
+		Status:   res.Status,
+		Progress: progress(res.Progress),
+	}
+	if res.Status == JobError {
+		status.Message = errorMessage(res.Output)
+	}
+
+	return status, nil
+}
+
+// progress decodes the progress of a job, which Routific reports as a
+// string, or as a number such as a percentage. Anything else is left out.
+func progress(raw json.RawMessage) string {
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+
+	return ""
+}
+
+// JobResult returns the schedule computed by the job with the given ID.
+// If the job failed, the error matches ErrJobFailed. If it is not finished
+// yet, the error matches ErrJobNotFinished.
+func (c *Client) JobResult(ctx context.Context, id string) (Schedule, error) {
+
+	res, err := c.fetchJob(ctx, id)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	return c.jobResult(res)
+}
+
+func (c *Client) submitJob(
+	ctx context.Context,
+	visits interface{},
+	path string,
+) (Job, error) {
+
+	jobJSON, err := c.post(ctx, visits, path)
+	if err != nil {
+		return Job{}, err
+	}
+
+	var job struct {
+		ID string `json:"job_id"`
+	}
+
+	if err := json.Unmarshal(jobJSON, &job); err != nil {
+		return Job{}, err
+	}
+
+	return Job{ID: job.ID, SubmittedAt: time.Now(), Endpoint: path}, nil
+}
+
+func (c *Client) fetchJob(ctx context.Context, id string) (jobResponse, error) {
+
+	response, err := c.get(ctx, jobURL(id))
+	if err != nil {
+		return jobResponse{}, withJobID(err, id)
+	}
+
+	var res jobResponse
+	if err := json.Unmarshal(response, &res); err != nil {
+		return jobResponse{}, err
+	}
+	if res.ID == "" {
+		res.ID = id
+	}
+
+	return res, nil
+}
+
+func (c *Client) jobResult(res jobResponse) (Schedule, error) {
+
+	switch res.Status {
+
+	case JobFinished:
+		var plan Schedule
+		if err := json.Unmarshal(res.Output, &plan); err != nil {
+			return Schedule{}, err
+		}
+		return plan, nil
+
+	case JobError:
+		return Schedule{}, &APIError{
+			Message: errorMessage(res.Output),
+			Body:    res.Output,
+			URL:     c.url(jobURL(res.ID)),
+			JobID:   res.ID,
+			Err:     ErrJobFailed,
+		}
+	}
+
+	return Schedule{}, &APIError{
+		Message: fmt.Sprintf("status %q", res.Status),
+		URL:     c.url(jobURL(res.ID)),
+		JobID:   res.ID,
+		Err:     ErrJobNotFinished,
+	}
+}
+
+// jobURL returns the API path of the job with the given ID.
+func jobURL(id string) string {
+	return fmt.Sprintf("%s/%s", jobPath, id)
+}
@@ -0,0 +1,125 @@
+package routific_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// job_test checks submitting a long-running job and resuming it from its
+// persisted handle.
+// Test data is defined in setup_test.
+
+func TestJobSubmitAndResume(t *testing.T) {
+
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method == "POST" && req.URL.Path == "/v1/pdp-long":
+				w.WriteHeader(202)
+				w.Write([]byte(`{"job_id": "job-42"}`))
+			case req.URL.Path == "/jobs/job-42":
+				polls++
+				switch polls {
+				case 1:
+					w.Write([]byte(`{"id": "job-42", "status": "pending"}`))
+				case 2:
+					w.Write([]byte(`{"id": "job-42", "status": "processing", ` +
+						`"progress": "Optimizing"}`))
+				default:
+					w.Write([]byte(`{"id": "job-42", "status": "finished", ` +
+						`"output": ` + pdpOutputJSON + `}`))
+				}
+			default:
+				w.WriteHeader(404)
+			}
+		}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL))
+
+	job, err := c.SubmitPDPJob(ctx, pdpInput)
+	assert.Nil(t, err)
+	assert.Equal(t, "job-42", job.ID)
+	assert.Equal(t, "/v1/pdp-long", job.Endpoint)
+	assert.False(t, job.SubmittedAt.IsZero())
+
+	// Persist the handle, then resume it as another process would
+	saved, err := json.Marshal(job)
+	assert.Nil(t, err)
+	var resumed r.Job
+	assert.Nil(t, json.Unmarshal(saved, &resumed))
+	assert.Equal(t, job.ID, resumed.ID)
+
+	status, err := c.JobStatus(ctx, resumed.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, r.JobStatus{ID: "job-42", Status: r.JobPending}, status)
+
+	_, err = c.JobResult(ctx, resumed.ID)
+	assert.True(t, errors.Is(err, r.ErrJobNotFinished))
+
+	output, err := c.JobResult(ctx, resumed.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, pdpOutput, output)
+}
+
+func TestJobStatusError(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"id": "job-7", "status": "error", ` +
+				`"output": "Visit order_1 is unreachable"}`))
+		}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL))
+
+	status, err := c.JobStatus(ctx, "job-7")
+	assert.Nil(t, err)
+	assert.Equal(t, r.JobError, status.Status)
+	assert.Equal(t, "Visit order_1 is unreachable", status.Message)
+
+	_, err = c.JobResult(ctx, "job-7")
+	assert.True(t, errors.Is(err, r.ErrJobFailed))
+}
+
+func TestJobStatusProgress(t *testing.T) {
+
+	progress := map[string]string{
+		"job-1": `"Optimizing"`,
+		"job-2": `42.5`,
+		"job-3": `{"error": "not a progress"}`,
+		"job-4": `null`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			id := strings.TrimPrefix(req.URL.Path, "/jobs/")
+			w.Write([]byte(`{"id": "` + id + `", "status": "processing", ` +
+				`"progress": ` + progress[id] + `}`))
+		}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL))
+
+	for id, want := range map[string]string{
+		"job-1": "Optimizing",
+		"job-2": "42.5",
+		"job-3": "",
+		"job-4": "",
+	} {
+		status, err := c.JobStatus(ctx, id)
+		assert.Nil(t, err)
+		assert.Equal(t, want, status.Progress, id)
+	}
+}
@@ -0,0 +1,115 @@
+package routific
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kmlDoc is a KML 2.2 document.
+// See [KML]: https://developers.google.com/kml/documentation/kmlreference
+type kmlDoc struct {
+	XMLName   xml.Name    `xml:"kml"`
+	Namespace string      `xml:"xmlns,attr"`
+	Name      string      `xml:"Document>name"`
+	Folders   []kmlFolder `xml:"Document>Folder"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string         `xml:"name"`
+	Description string         `xml:"description,omitempty"`
+	When        *time.Time     `xml:"TimeStamp>when,omitempty"`
+	Point       *kmlCoords     `xml:"Point,omitempty"`
+	LineString  *kmlLineString `xml:"LineString,omitempty"`
+}
+
+type kmlCoords struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// KML returns the routes of all vehicles as a KML document for Google Earth:
+// a folder for each vehicle with its stops as placemarks, in order and
+// named by Stop.Name, and its route as a line, along the decoded polylines
+// if the schedule has them, or else straight from stop to stop.
+// The error matches ErrInvalidInput if a polyline is malformed.
+func (s Schedule) KML(plan Plan) ([]byte, error) {
+	return s.kml(plan, nil)
+}
+
+// KML is like Schedule.KML, with the arrival times of the stops as
+// timestamps.
+func (t TimedSchedule) KML(plan Plan) ([]byte, error) {
+	return t.Schedule.kml(plan, t.Routes)
+}
+
+// kml writes the KML document, with the arrival times of timed if given.
+func (s Schedule) kml(plan Plan, timed map[string][]TimedStop) ([]byte, error) {
+
+	doc := kmlDoc{
+		Namespace: "http://www.opengis.net/kml/2.2",
+		Name:      "Routific schedule",
+	}
+
+	for _, vehicle := range sortedKeys(s.Solution) {
+		folder := kmlFolder{Name: vehicle}
+		var line []string
+
+		for _, stop := range routeStops(plan, vehicle, s.Solution[vehicle]) {
+			coords := kmlPosition(stop.loc)
+			p := kmlPlacemark{
+				Name:        fmt.Sprintf("%d. %s", stop.seq, stop.label()),
+				Description: stop.times(),
+				Point:       &kmlCoords{coords},
+			}
+			if route := timed[vehicle]; stop.seq < len(route) {
+				at := route[stop.seq].Arrival
+				p.When = &at
+			}
+			folder.Placemarks = append(folder.Placemarks, p)
+			line = append(line, coords)
+		}
+
+		path, err := s.Geometry(vehicle)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > 0 {
+			line = line[:0]
+			for _, loc := range path {
+				line = append(line, kmlPosition(loc))
+			}
+		}
+
+		if len(line) > 1 {
+			folder.Placemarks = append(folder.Placemarks, kmlPlacemark{
+				Name: vehicle + " route",
+				LineString: &kmlLineString{
+					Tessellate:  1,
+					Coordinates: strings.Join(line, " "),
+				},
+			})
+		}
+
+		doc.Folders = append(doc.Folders, folder)
+	}
+
+	return marshalXML(doc)
+}
+
+// kmlPosition writes loc as "lng,lat".
+func kmlPosition(loc Location) string {
+	return strconv.FormatFloat(float64(loc.Longitude), 'g', -1, 32) + "," +
+		strconv.FormatFloat(float64(loc.Latitude), 'g', -1, 32)
+}
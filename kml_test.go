@@ -0,0 +1,55 @@
+package routific_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// kml_test checks the KML export of routes. The schedule is defined in
+// gpx_test and the plan in setup_test.
+
+func TestKML(t *testing.T) {
+
+	b, err := gpxSchedule.KML(vrpInput)
+	assert.Nil(t, err)
+
+	var doc struct {
+		Folders []struct {
+			Name       string `xml:"name"`
+			Placemarks []struct {
+				Name  string `xml:"name"`
+				Desc  string `xml:"description"`
+				When  string `xml:"TimeStamp>when"`
+				Point string `xml:"Point>coordinates"`
+				Line  string `xml:"LineString>coordinates"`
+			} `xml:"Placemark"`
+		} `xml:"Document>Folder"`
+	}
+	assert.Nil(t, xml.Unmarshal(b, &doc))
+	assert.True(t, strings.HasPrefix(string(b), `<?xml`))
+	assert.Contains(t, string(b), `<kml xmlns="http://www.opengis.net/kml/2.2">`)
+
+	assert.Len(t, doc.Folders, 1)
+	folder := doc.Folders[0]
+	assert.Equal(t, "vehicle_1", folder.Name)
+	assert.Len(t, folder.Placemarks, 4)
+
+	assert.Equal(t, "1. 800 Robson", folder.Placemarks[1].Name)
+	assert.Equal(t, "Arrival 08:20, finish 08:30", folder.Placemarks[1].Desc)
+	assert.Equal(t, "-123.121185,49.28192", folder.Placemarks[1].Point)
+	assert.Equal(t, "", folder.Placemarks[1].When)
+
+	assert.Equal(t, "vehicle_1 route", folder.Placemarks[3].Name)
+	assert.Equal(t, "-123.08733,49.255363 -123.121185,49.28192 -123.08733,49.255363",
+		folder.Placemarks[3].Line)
+
+	loc := time.FixedZone("PDT", -7*60*60)
+	timed := gpxSchedule.Materialize(time.Date(2022, 6, 1, 0, 0, 0, 0, loc), loc)
+	b, err = timed.KML(vrpInput)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), "<when>2022-06-01T08:20:00-07:00</when>")
+}
@@ -0,0 +1,161 @@
+package routific
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Load is the load of a visit or the capacity of a vehicle. It is either a
+// single quantity, written as a JSON number, or quantities in named
+// dimensions such as "weight" and "volume", written as a JSON object.
+// A single quantity is kept under the empty dimension name.
+// See [Capacity]: https://docs.routific.com/reference/capacity
+type Load map[string]float64
+
+// Units returns the single-quantity load n.
+func Units(n float64) Load {
+	return Load{"": n}
+}
+
+// OrUnit returns l, or a single unit if l is nil, as Routific counts a visit
+// or an order without a load.
+func (l Load) OrUnit() Load {
+
+	if l == nil {
+		return Units(1)
+	}
+
+	return l
+}
+
+// Dimensions returns the names of the dimensions of l, sorted.
+func (l Load) Dimensions() []string {
+	return sortedKeys(l)
+}
+
+// IsZero reports whether every quantity of l is zero.
+func (l Load) IsZero() bool {
+
+	for _, q := range l {
+		if q != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Add returns the sum of l and o in every dimension of either.
+func (l Load) Add(o Load) Load {
+
+	sum := make(Load, len(l))
+	for dim, q := range l {
+		sum[dim] = q
+	}
+	for dim, q := range o {
+		sum[dim] += q
+	}
+
+	return sum
+}
+
+// Sub returns l minus o in every dimension of either.
+func (l Load) Sub(o Load) Load {
+
+	diff := make(Load, len(l))
+	for dim, q := range l {
+		diff[dim] = q
+	}
+	for dim, q := range o {
+		diff[dim] -= q
+	}
+
+	return diff
+}
+
+// Fits reports whether l is within capacity in every dimension. Dimensions
+// missing from capacity are unlimited, so an empty capacity fits any load.
+func (l Load) Fits(capacity Load) bool {
+
+	for dim, q := range l {
+		if c, ok := capacity[dim]; ok && q > c {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Utilisation returns the highest fraction of capacity used by l in any
+// dimension that capacity limits, e.g. 0.75 for 3 units of 4. It is 0 if
+// capacity limits none of the dimensions of l.
+func (l Load) Utilisation(capacity Load) float64 {
+
+	var u float64
+	for dim, q := range l {
+		if c, ok := capacity[dim]; ok && c > 0 && q/c > u {
+			u = q / c
+		}
+	}
+
+	return u
+}
+
+// MarshalJSON writes a single quantity as a number, and named dimensions as
+// an object.
+func (l Load) MarshalJSON() ([]byte, error) {
+
+	if q, ok := l[""]; ok {
+		if len(l) > 1 {
+			return nil, fmt.Errorf(
+				"%w: load mixes a single quantity with named dimensions",
+				ErrInvalidInput)
+		}
+		return json.Marshal(q)
+	}
+
+	return json.Marshal(map[string]float64(l))
+}
+
+// UnmarshalJSON reads a number as a single quantity, and an object as named
+// dimensions.
+func (l *Load) UnmarshalJSON(data []byte) error {
+
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var q float64
+	if err := json.Unmarshal(data, &q); err == nil {
+		*l = Units(q)
+		return nil
+	}
+
+	var dims map[string]float64
+	if err := json.Unmarshal(data, &dims); err != nil {
+		return fmt.Errorf("%w: load %s, want a number or an object of numbers",
+			ErrInvalidInput, data)
+	}
+
+	*l = dims
+	return nil
+}
+
+// String formats l for messages, e.g. "3" or "volume=2 weight=120".
+func (l Load) String() string {
+
+	if q, ok := l[""]; ok && len(l) == 1 {
+		return fmt.Sprintf("%g", q)
+	}
+
+	s := ""
+	for i, dim := range l.Dimensions() {
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprintf("%s=%g", dim, l[dim])
+	}
+
+	return s
+}
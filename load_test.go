@@ -0,0 +1,77 @@
+package routific_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// load_test checks JSON encoding, arithmetic, and capacity checks of Load.
+
+func TestLoadJSON(t *testing.T) {
+
+	var v r.Visit
+	err := json.Unmarshal([]byte(`{"load": 3}`), &v)
+	assert.Nil(t, err)
+	assert.Equal(t, r.Units(3), v.Load)
+
+	var o r.PickDropOrder
+	err = json.Unmarshal([]byte(`{"load": {"weight": 120, "volume": 3}}`), &o)
+	assert.Nil(t, err)
+	assert.Equal(t, r.Load{"weight": 120, "volume": 3}, o.Load)
+
+	b, err := json.Marshal(r.Vehicle{Capacity: r.Units(300)})
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"capacity":300`)
+
+	b, err = json.Marshal(o.Load)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"weight": 120, "volume": 3}`, string(b))
+
+	b, err = json.Marshal(r.Visit{})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(b), "load")
+
+	_, err = json.Marshal(r.Load{"": 1, "weight": 2})
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+
+	err = json.Unmarshal([]byte(`{"load": "heavy"}`), &v)
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+}
+
+func TestLoadArithmetic(t *testing.T) {
+
+	a := r.Load{"weight": 100, "volume": 2}
+	b := r.Load{"weight": 20, "pallets": 1}
+
+	assert.Equal(t, r.Load{"weight": 120, "volume": 2, "pallets": 1}, a.Add(b))
+	assert.Equal(t, r.Load{"weight": 80, "volume": 2, "pallets": -1}, a.Sub(b))
+	assert.Equal(t, r.Load{"weight": 100, "volume": 2}, a, "a is unchanged")
+
+	assert.Equal(t, a, a.OrUnit())
+	assert.Equal(t, r.Units(1), r.Load(nil).OrUnit())
+
+	assert.Equal(t, []string{"volume", "weight"}, a.Dimensions())
+	assert.True(t, r.Load{}.IsZero())
+	assert.True(t, a.Sub(a).IsZero())
+	assert.False(t, a.IsZero())
+
+	assert.Equal(t, "volume=2 weight=100", a.String())
+	assert.Equal(t, "3", r.Units(3).String())
+}
+
+func TestLoadFits(t *testing.T) {
+
+	capacity := r.Load{"weight": 150, "volume": 4}
+
+	assert.True(t, r.Load{"weight": 120, "volume": 3}.Fits(capacity))
+	assert.False(t, r.Load{"weight": 160}.Fits(capacity))
+	assert.True(t, r.Load{"pallets": 9}.Fits(capacity), "unlimited dimension")
+	assert.True(t, r.Units(1000).Fits(nil))
+
+	assert.InDelta(t, 0.8, r.Load{"weight": 120, "volume": 3}.Utilisation(capacity), 1e-9)
+	assert.Equal(t, 0.0, r.Load{"pallets": 9}.Utilisation(capacity))
+}
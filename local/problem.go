@@ -0,0 +1,278 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/slamethendry/routific"
+)
+
+// endOfDay is the latest time of the day, in minutes since midnight.
+const endOfDay float64 = 24 * 60
+
+// window is a time window in minutes since midnight.
+type window struct {
+	start, end float64
+}
+
+// node is a visit or a depot.
+type node struct {
+	id       string
+	loc      routific.Location
+	windows  []window // visits only
+	duration float64  // minutes
+	load     routific.Load
+	vtype    string
+}
+
+// vehicle is a fleet entry, with its depots as node indices.
+type vehicle struct {
+	id         string
+	start      int
+	end        int // -1 if the route ends at the last visit
+	shiftStart float64
+	shiftEnd   float64
+	capacity   routific.Load // empty means unlimited
+	vtype      string
+	speed      float64 // factor applied to travel times
+	breaks     []pause // sorted by start
+}
+
+// pause is a driver break in minutes since midnight. It lasts length minutes
+// and starts between start and latest.
+type pause struct {
+	id            string
+	start, latest float64
+	length        float64
+}
+
+// problem is a VRPlan in a form suitable for solving.
+type problem struct {
+	nodes    []node // visits first, then depots
+	visits   int    // number of visits, i.e. nodes[:visits]
+	vehicles []vehicle
+	travel   [][]float64 // minutes between nodes at normal speed
+	km       [][]float64 // distance between nodes
+}
+
+// newProblem converts the plan and gets travel times between all of its
+// locations from the matrix provider of s.
+func (s *Solver) newProblem(
+	ctx context.Context,
+	plan routific.VRPlan,
+) (*problem, error) {
+
+	p := &problem{}
+
+	// Sort keys so that the same plan always gives the same schedule
+	visitIDs := make([]string, 0, len(plan.Visits))
+	for id := range plan.Visits {
+		visitIDs = append(visitIDs, id)
+	}
+	sort.Strings(visitIDs)
+
+	for _, id := range visitIDs {
+		n, err := newVisit(id, plan.Visits[id])
+		if err != nil {
+			return nil, err
+		}
+		p.nodes = append(p.nodes, n)
+	}
+	p.visits = len(p.nodes)
+
+	vehicleIDs := make([]string, 0, len(plan.Fleet))
+	for id := range plan.Fleet {
+		vehicleIDs = append(vehicleIDs, id)
+	}
+	sort.Strings(vehicleIDs)
+
+	traffic := speedFactor(plan.Options.Traffic)
+
+	for _, id := range vehicleIDs {
+		v, err := p.newVehicle(id, plan.Fleet[id])
+		if err != nil {
+			return nil, err
+		}
+		v.speed *= traffic
+		p.vehicles = append(p.vehicles, v)
+	}
+
+	locations := make([]routific.Location, len(p.nodes))
+	for i := range p.nodes {
+		locations[i] = p.nodes[i].loc
+	}
+
+	m, err := s.provider().Matrix(ctx, locations)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Durations) != len(locations) || len(m.Distances) != len(locations) {
+		return nil, fmt.Errorf("%w: matrix of %d locations, want %d",
+			routific.ErrInvalidInput, len(m.Durations), len(locations))
+	}
+	p.travel, p.km = m.Durations, m.Distances
+
+	return p, nil
+}
+
+func newVisit(id string, v routific.Visit) (node, error) {
+
+	n := node{
+		id:       id,
+		loc:      v.Location,
+		duration: float64(v.Duration),
+		load:     v.Load.OrUnit(),
+		vtype:    v.Type,
+	}
+
+	windows := v.TimeWindows
+	if len(windows) == 0 {
+		windows = []routific.TimeWindow{{Start: v.Start, End: v.End}}
+	}
+
+	for _, tw := range windows {
+		start, err := minutes(tw.Start, 0)
+		if err != nil {
+			return node{}, fmt.Errorf("visit %s: %w", id, err)
+		}
+		end, err := minutes(tw.End, endOfDay)
+		if err != nil {
+			return node{}, fmt.Errorf("visit %s: %w", id, err)
+		}
+		n.windows = append(n.windows, window{start, end})
+	}
+
+	sort.Slice(n.windows, func(i, j int) bool {
+		return n.windows[i].start < n.windows[j].start
+	})
+
+	return n, nil
+}
+
+func (p *problem) newVehicle(id string, v routific.Vehicle) (vehicle, error) {
+
+	shiftStart, err := minutes(v.ShiftStart, 0)
+	if err != nil {
+		return vehicle{}, fmt.Errorf("vehicle %s: %w", id, err)
+	}
+	shiftEnd, err := minutes(v.ShiftEnd, endOfDay)
+	if err != nil {
+		return vehicle{}, fmt.Errorf("vehicle %s: %w", id, err)
+	}
+
+	veh := vehicle{
+		id:         id,
+		start:      p.addDepot(id, v.StartLocation),
+		end:        -1,
+		shiftStart: shiftStart,
+		shiftEnd:   shiftEnd,
+		capacity:   v.Capacity,
+		vtype:      v.Type,
+		speed:      speedFactor(v.Speed),
+	}
+	if !isZero(v.EndLocation) {
+		veh.end = p.addDepot(id, v.EndLocation)
+	}
+
+	for _, b := range v.Breaks {
+		start, err := minutes(b.Start, 0)
+		if err != nil {
+			return vehicle{}, fmt.Errorf("vehicle %s break %s: %w", id, b.ID, err)
+		}
+		end, err := minutes(b.End, endOfDay)
+		if err != nil {
+			return vehicle{}, fmt.Errorf("vehicle %s break %s: %w", id, b.ID, err)
+		}
+		length := float64(b.Duration)
+		if length <= 0 {
+			length = end - start
+		}
+		veh.breaks = append(veh.breaks, pause{
+			id:     b.ID,
+			start:  start,
+			latest: end - length,
+			length: length,
+		})
+	}
+	sort.Slice(veh.breaks, func(i, j int) bool {
+		return veh.breaks[i].start < veh.breaks[j].start
+	})
+
+	return veh, nil
+}
+
+// addDepot adds a depot node and returns its index. Depots without an ID are
+// named after their vehicle.
+func (p *problem) addDepot(vehicleID string, loc routific.Location) int {
+
+	id := loc.ID
+	if id == "" {
+		id = vehicleID
+	}
+
+	p.nodes = append(p.nodes, node{id: id, loc: loc})
+	return len(p.nodes) - 1
+}
+
+// distance returns the km of road between nodes a and b.
+func (p *problem) distance(a, b int) float64 {
+	return p.km[a][b]
+}
+
+// minutes converts a time of day into minutes since midnight, returning def
+// for the zero value, which means unset.
+func minutes(t routific.ClockTime, def float64) (float64, error) {
+
+	if t == 0 {
+		return def, nil
+	}
+	if !t.Valid() {
+		return 0, fmt.Errorf("%w: time %s out of range", routific.ErrInvalidInput, t)
+	}
+
+	return float64(t), nil
+}
+
+// clock converts minutes since midnight into a time of day. Times that
+// round to midnight at the end of the day are given as 23:59, the latest
+// valid time.
+func clock(minutes float64) routific.ClockTime {
+	return routific.ClockTime(math.Min(math.Round(minutes), endOfDay-1))
+}
+
+// fits reports whether load plus extra is within capacity, without
+// allocating their sum.
+func fits(load, extra, capacity routific.Load) bool {
+
+	for dim, c := range capacity {
+		if load[dim]+extra[dim] > c {
+			return false
+		}
+	}
+
+	return true
+}
+
+// speedFactor converts a Routific speed or traffic setting into a factor
+// applied to travel speed.
+func speedFactor(s string) float64 {
+
+	switch s {
+	case "faster":
+		return 1.5
+	case "fast":
+		return 1.25
+	case "slow":
+		return 0.75
+	case "very slow":
+		return 0.5
+	}
+
+	return 1
+}
+
+func isZero(loc routific.Location) bool {
+	return loc == routific.Location{}
+}
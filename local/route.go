@@ -0,0 +1,552 @@
+package local
+
+import (
+	"context"
+	"math"
+
+	"github.com/slamethendry/routific"
+)
+
+// leg is the timing of one visit along a route, in minutes since midnight.
+type leg struct {
+	arrival float64
+	start   float64 // when service starts, after waiting for the window
+	finish  float64
+}
+
+// rest is a break taken along a route.
+type rest struct {
+	id            string
+	after         int // number of visits before the break
+	start, finish float64
+}
+
+// plan is the timing of a whole route.
+type plan struct {
+	depart float64 // from the start depot
+	legs   []leg
+	breaks []rest
+	back   float64 // arrival at the end depot, or finish of the last visit
+	travel float64 // minutes driving
+	idle   float64 // minutes waiting
+}
+
+// drive returns the minutes that v needs from node a to node b.
+func (p *problem) drive(v *vehicle, a, b int) float64 {
+	return p.travel[a][b] / v.speed
+}
+
+// canServe reports whether v may serve visit n at all.
+func (p *problem) canServe(v *vehicle, n int) bool {
+
+	visit := &p.nodes[n]
+	if visit.vtype != "" && visit.vtype != v.vtype {
+		return false
+	}
+
+	return visit.load.Fits(v.capacity)
+}
+
+// serviceStart returns when service of visit n can start if the vehicle
+// arrives at t, or false if every time window has closed by then.
+func (p *problem) serviceStart(n int, t float64) (float64, bool) {
+
+	for _, w := range p.nodes[n].windows {
+		if t <= w.end {
+			return math.Max(t, w.start), true
+		}
+	}
+
+	return 0, false
+}
+
+// state is how far a vehicle has got along a route: where it is, when it
+// can leave, the next break to take, and the minutes driven and waited.
+type state struct {
+	at     int
+	t      float64
+	next   int
+	travel float64
+	idle   float64
+}
+
+// simulate drives v along route, leaving the depot at the start of the shift,
+// and reports whether the route keeps to the time windows, the shift, the
+// capacity and the breaks. A break is taken where the vehicle is, as late as
+// possible: just before the visit that would otherwise finish after the
+// latest start of the break.
+func (p *problem) simulate(v *vehicle, route []int) (plan, bool) {
+
+	r := plan{depart: v.shiftStart, legs: make([]leg, len(route))}
+	s, _, ok := p.run(v, route, &r, nil)
+	r.travel, r.idle, r.back = s.travel, s.idle, s.t
+
+	return r, ok
+}
+
+// run drives v along route as simulate does. It records the timing in r and
+// the state before each visit in states, where they are not nil, and returns
+// the state at the end of the route and the load of the vehicle.
+func (p *problem) run(v *vehicle, route []int, r *plan, states []state) (state, routific.Load, bool) {
+
+	s := state{at: v.start, t: v.shiftStart}
+	load := routific.Load{}
+
+	for i, n := range route {
+		if states != nil {
+			states[i] = s
+		}
+		if !p.canServe(v, n) {
+			return s, load, false
+		}
+		for dim, q := range p.nodes[n].load {
+			load[dim] += q
+		}
+		if !load.Fits(v.capacity) {
+			return s, load, false
+		}
+		if !p.visit(v, &s, i, n, r) {
+			return s, load, false
+		}
+	}
+	if states != nil {
+		states[len(route)] = s
+	}
+
+	return s, load, p.finish(v, &s, len(route), r)
+}
+
+// visit drives v from where s is to visit n, the i-th of the route, taking
+// the next break first if it cannot wait until after the visit, and serves
+// it. It records the timing in r, if not nil, and reports whether the visit
+// can be served in time.
+func (p *problem) visit(v *vehicle, s *state, i, n int, r *plan) bool {
+
+	d := p.drive(v, s.at, n)
+	var arrival, start float64
+	for {
+		var ok bool
+		arrival = s.t + d
+		start, ok = p.serviceStart(n, arrival)
+		if !ok {
+			return false
+		}
+		if s.next == len(v.breaks) ||
+			v.breaks[s.next].latest >= start+p.nodes[n].duration {
+			break
+		}
+		if !p.pause(v, s, i, r) {
+			return false
+		}
+	}
+
+	s.travel += d
+	s.idle += start - arrival
+	s.t = start + p.nodes[n].duration
+	s.at = n
+	if r != nil {
+		r.legs[i] = leg{arrival: arrival, start: start, finish: s.t}
+	}
+
+	return true
+}
+
+// pause takes the next break of v where s is, after the given number of
+// visits, recording it in r if not nil. It reports false if it is too late
+// for the break.
+func (p *problem) pause(v *vehicle, s *state, after int, r *plan) bool {
+
+	b := v.breaks[s.next]
+	start := math.Max(s.t, b.start)
+	if start > b.latest {
+		return false
+	}
+
+	s.idle += start - s.t
+	s.t = start + b.length
+	s.next++
+	if r != nil {
+		r.breaks = append(r.breaks, rest{b.id, after, start, s.t})
+	}
+
+	return true
+}
+
+// finish drives v from where s is to its end depot after the given number
+// of visits, taking the breaks still due, and reports whether it is back
+// before the end of the shift.
+func (p *problem) finish(v *vehicle, s *state, visits int, r *plan) bool {
+
+	var d float64
+	if v.end >= 0 {
+		d = p.drive(v, s.at, v.end)
+	}
+	// Breaks that start before the end of the day are still due
+	for visits > 0 && s.next < len(v.breaks) && v.breaks[s.next].start < s.t+d {
+		if !p.pause(v, s, visits, r) {
+			return false
+		}
+	}
+	s.travel += d
+	s.t += d
+
+	return s.t <= v.shiftEnd
+}
+
+// cost ranks feasible routes: shorter driving first, then shorter days.
+func (r plan) cost() float64 {
+	return r.travel + 1e-3*(r.back-r.depart)
+}
+
+// construct builds the routes of all vehicles, one after the other, by
+// visiting the nearest feasible visit next. It returns the routes by vehicle
+// index and the visits left unserved.
+func (p *problem) construct() ([][]int, []bool) {
+
+	served := make([]bool, p.visits)
+	routes := make([][]int, len(p.vehicles))
+
+	for vi := range p.vehicles {
+		v := &p.vehicles[vi]
+
+		at := v.start
+		t := v.shiftStart
+		var load routific.Load
+		rejected := map[int]bool{} // visits that break the breaks
+
+		for {
+			best, bestCost := -1, math.Inf(1)
+			var bestFinish float64
+
+			for n := 0; n < p.visits; n++ {
+				if served[n] || rejected[n] || !p.canServe(v, n) {
+					continue
+				}
+				if !fits(load, p.nodes[n].load, v.capacity) {
+					continue
+				}
+				arrival := t + p.drive(v, at, n)
+				start, ok := p.serviceStart(n, arrival)
+				if !ok {
+					continue
+				}
+				finish := start + p.nodes[n].duration
+				back := finish
+				if v.end >= 0 {
+					back += p.drive(v, n, v.end)
+				}
+				if back > v.shiftEnd {
+					continue
+				}
+				if cost := start - t; cost < bestCost {
+					best, bestCost, bestFinish = n, cost, finish
+				}
+			}
+
+			if best < 0 {
+				break
+			}
+
+			// Breaks may delay the visit, so check the whole route
+			if len(v.breaks) > 0 {
+				r, ok := p.simulate(v, append(routes[vi], best))
+				if !ok {
+					rejected[best] = true
+					continue
+				}
+				bestFinish = r.legs[len(r.legs)-1].finish
+			}
+
+			served[best] = true
+			routes[vi] = append(routes[vi], best)
+			load = load.Add(p.nodes[best].load)
+			at, t = best, bestFinish
+		}
+	}
+
+	return routes, served
+}
+
+// trace is a feasible route with the state of its vehicle before each
+// visit, so that a change to the route can be evaluated from where it
+// starts rather than from the depot.
+type trace struct {
+	route  []int
+	states []state // before each visit, then after the last one
+	end    state   // back at the end depot
+	load   routific.Load
+	cost   float64
+}
+
+// trace drives v along route, reporting false if it is not feasible.
+func (p *problem) trace(v *vehicle, route []int) (*trace, bool) {
+
+	tr := &trace{route: route, states: make([]state, len(route)+1)}
+
+	var ok bool
+	tr.end, tr.load, ok = p.run(v, route, nil, tr.states)
+	tr.cost = tr.end.cost(v)
+
+	return tr, ok
+}
+
+// cost ranks the route of v that ends in s, as plan.cost does.
+func (s state) cost(v *vehicle) float64 {
+	return s.travel + 1e-3*(s.t-v.shiftStart)
+}
+
+// bound returns the least cost of a route of v driving travel minutes:
+// the day lasts at least as long as the driving.
+func bound(travel float64) float64 {
+	return travel * (1 + 1e-3)
+}
+
+// eval returns the cost of candidate, a change of tr.route for v, and whether
+// it is feasible. The candidate must be the same as tr.route up to position
+// from and after position to, where it may be shifted by visits inserted
+// in between. It must not add load.
+func (p *problem) eval(v *vehicle, tr *trace, candidate []int, from, to int) (float64, bool) {
+
+	shift := len(candidate) - len(tr.route)
+	s := tr.states[from]
+
+	for i := from; i < len(candidate); i++ {
+		if !p.visit(v, &s, i, candidate[i], nil) {
+			return 0, false
+		}
+		// Once back in step with tr, the rest of the route is the same
+		if o := tr.states[i+1-shift]; i >= to && s.at == o.at && s.next == o.next &&
+			math.Abs(s.t-o.t) < 1e-9 {
+			s.travel += tr.end.travel - o.travel
+			s.t = tr.end.t
+			return s.cost(v), true
+		}
+	}
+
+	if !p.finish(v, &s, len(candidate), nil) {
+		return 0, false
+	}
+
+	return s.cost(v), true
+}
+
+// hop returns the minutes that v drives from node a to node b, which is -1
+// for the end of a route without an end depot.
+func (p *problem) hop(v *vehicle, a, b int) float64 {
+
+	if b < 0 {
+		return 0
+	}
+
+	return p.drive(v, a, b)
+}
+
+// before returns the node before route[i]: the previous visit or the start
+// depot.
+func before(v *vehicle, route []int, i int) int {
+
+	if i == 0 {
+		return v.start
+	}
+
+	return route[i-1]
+}
+
+// after returns the node after route[i]: the next visit, the end depot, or
+// -1 for none.
+func after(v *vehicle, route []int, i int) int {
+
+	if i == len(route)-1 {
+		return v.end
+	}
+
+	return route[i+1]
+}
+
+// improve applies 2-opt and or-opt moves to a route for as long as they make
+// it cheaper, or until ctx is done. Moves are only simulated if the change
+// in driving they make leaves them a chance of being cheaper.
+func (p *problem) improve(ctx context.Context, v *vehicle, route []int) []int {
+
+	tr, ok := p.trace(v, route)
+	if !ok {
+		return route
+	}
+
+	// try replaces tr with candidate if it is feasible and cheaper
+	try := func(candidate []int, from, to int) bool {
+		cost, ok := p.eval(v, tr, candidate, from, to)
+		if !ok || cost >= tr.cost-1e-9 {
+			return false
+		}
+		tr, _ = p.trace(v, candidate)
+		return true
+	}
+
+	for improved := true; improved && ctx.Err() == nil; {
+		improved = false
+
+		// 2-opt: reverse route[i:j+1]
+		for i := 0; i < len(tr.route)-1 && ctx.Err() == nil; i++ {
+			var inner float64 // change in driving within the segment
+			for j := i + 1; j < len(tr.route); j++ {
+				route := tr.route
+				inner += p.drive(v, route[j], route[j-1]) - p.drive(v, route[j-1], route[j])
+				a, b := before(v, route, i), after(v, route, j)
+				delta := inner + p.hop(v, a, route[j]) + p.hop(v, route[i], b) -
+					p.hop(v, a, route[i]) - p.hop(v, route[j], b)
+				if bound(tr.end.travel+delta) >= tr.cost-1e-9 {
+					continue
+				}
+				if try(reverse(route, i, j), i, j) {
+					inner, improved = -inner, true
+				}
+			}
+		}
+
+		// or-opt: move a segment of up to 3 visits elsewhere
+		for size := 1; size <= 3; size++ {
+			for i := 0; i+size <= len(tr.route) && ctx.Err() == nil; i++ {
+				for j := 0; j <= len(tr.route)-size; j++ {
+					if j == i {
+						continue
+					}
+					delta := p.moveDelta(v, tr.route, i, size, j)
+					if bound(tr.end.travel+delta) >= tr.cost-1e-9 {
+						continue
+					}
+					from, to := i, j+size-1
+					if j < i {
+						from, to = j, i+size-1
+					}
+					if try(move(tr.route, i, size, j), from, to) {
+						improved = true
+					}
+				}
+			}
+		}
+	}
+
+	return tr.route
+}
+
+// moveDelta returns the change in driving of v from moving the segment of
+// size visits starting at route[i] to start at j of the remaining route.
+func (p *problem) moveDelta(v *vehicle, route []int, i, size, j int) float64 {
+
+	first, last := route[i], route[i+size-1]
+	a, b := before(v, route, i), after(v, route, i+size-1)
+
+	// rest returns the k-th node of the route without the segment
+	rest := func(k int) int {
+		if k >= i {
+			k += size
+		}
+		if k == len(route) {
+			return v.end
+		}
+		return route[k]
+	}
+	x, y := v.start, rest(j)
+	if j > 0 {
+		x = rest(j - 1)
+	}
+
+	return p.hop(v, a, b) - p.hop(v, a, first) - p.hop(v, last, b) +
+		p.hop(v, x, first) + p.hop(v, last, y) - p.hop(v, x, y)
+}
+
+// insert adds unserved visits to the routes where they add the least
+// driving, as long as the routes stay feasible, or until ctx is done.
+func (p *problem) insert(ctx context.Context, routes [][]int, served []bool) {
+
+	traces := make([]*trace, len(routes)) // nil until needed, or if infeasible
+	traced := make([]bool, len(routes))
+
+	for n := 0; n < p.visits && ctx.Err() == nil; n++ {
+		if served[n] {
+			continue
+		}
+
+		bestV, bestPos, bestCost := -1, -1, math.Inf(1)
+
+		for vi := range p.vehicles {
+			v := &p.vehicles[vi]
+			if !p.canServe(v, n) {
+				continue
+			}
+			if !traced[vi] {
+				if tr, ok := p.trace(v, routes[vi]); ok {
+					traces[vi] = tr
+				}
+				traced[vi] = true
+			}
+			tr := traces[vi]
+			if tr == nil || !fits(tr.load, p.nodes[n].load, v.capacity) {
+				continue
+			}
+			for pos := 0; pos <= len(tr.route); pos++ {
+				a, b := v.start, v.end
+				if pos > 0 {
+					a = tr.route[pos-1]
+				}
+				if pos < len(tr.route) {
+					b = tr.route[pos]
+				}
+				delta := p.hop(v, a, n) + p.hop(v, n, b) - p.hop(v, a, b)
+				if bound(tr.end.travel+delta)-tr.cost >= bestCost {
+					continue
+				}
+				cost, ok := p.eval(v, tr, insertAt(tr.route, pos, n), pos, pos)
+				if ok && cost-tr.cost < bestCost {
+					bestV, bestPos, bestCost = vi, pos, cost-tr.cost
+				}
+			}
+		}
+
+		if bestV >= 0 {
+			routes[bestV] = insertAt(routes[bestV], bestPos, n)
+			served[n] = true
+			traced[bestV] = false
+		}
+	}
+}
+
+// reverse returns a copy of route with route[i:j+1] reversed.
+func reverse(route []int, i, j int) []int {
+
+	out := append([]int(nil), route...)
+	for ; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return out
+}
+
+// move returns a copy of route with the segment of size visits starting at
+// i moved to start at j of the remaining route.
+func move(route []int, i, size, j int) []int {
+
+	segment := route[i : i+size]
+	rest := make([]int, 0, len(route)-size)
+	rest = append(rest, route[:i]...)
+	rest = append(rest, route[i+size:]...)
+
+	out := make([]int, 0, len(route))
+	out = append(out, rest[:j]...)
+	out = append(out, segment...)
+	out = append(out, rest[j:]...)
+
+	return out
+}
+
+// insertAt returns a copy of route with n inserted at pos.
+func insertAt(route []int, pos, n int) []int {
+
+	out := make([]int, 0, len(route)+1)
+	out = append(out, route[:pos]...)
+	out = append(out, n)
+	out = append(out, route[pos:]...)
+
+	return out
+}
@@ -0,0 +1,242 @@
+// Package local solves vehicle routing plans offline, as a fallback for when
+// the Routific API is unavailable, or for development.
+//
+// The solver builds routes by visiting the nearest feasible visit next, then
+// improves each route with 2-opt and or-opt moves. Travel times come from a
+// routific.MatrixProvider, by default estimated from the straight-line
+// (haversine) distance between locations. It keeps to
+// shift times, visit time windows, service durations, vehicle capacities,
+// vehicle types and driver breaks, but its schedules are not as good as
+// Routific's. Breaks are taken wherever the vehicle is at the time; their
+// locations are ignored.
+package local
+
+import (
+	"context"
+	"math"
+
+	"github.com/slamethendry/routific"
+)
+
+// Solver is an offline routific.Solver for VRP plans.
+type Solver struct {
+	speed    float64 // km/h
+	circuity float64
+	matrix   routific.MatrixProvider
+}
+
+var _ routific.Solver = (*Solver)(nil)
+
+// Option configures a Solver.
+type Option func(*Solver)
+
+// WithSpeed sets the average driving speed in km/h. The default is 40.
+func WithSpeed(kmh float64) Option {
+	return func(s *Solver) {
+		s.speed = kmh
+	}
+}
+
+// WithCircuity sets the ratio of road distance to straight-line distance.
+// The default is 1.3, typical of city streets.
+func WithCircuity(ratio float64) Option {
+	return func(s *Solver) {
+		s.circuity = ratio
+	}
+}
+
+// WithMatrix sets the provider of travel times and distances, e.g. a
+// routific.CachingMatrix to reuse them across plans, or a routific.MatrixFile
+// of road travel times. It overrides WithSpeed and WithCircuity.
+func WithMatrix(p routific.MatrixProvider) Option {
+	return func(s *Solver) {
+		s.matrix = p
+	}
+}
+
+// NewSolver returns a Solver configured by opts.
+func NewSolver(opts ...Option) *Solver {
+
+	s := &Solver{speed: 40, circuity: 1.3}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// SolveVRP implements routific.Solver. When ctx is done, it stops improving
+// the routes and returns the best schedule found so far, so that it can
+// still serve as a fallback close to a deadline.
+func (s *Solver) SolveVRP(
+	ctx context.Context,
+	plan routific.VRPlan,
+) (routific.Schedule, error) {
+
+	p, err := s.newProblem(ctx, plan)
+	if err != nil {
+		return routific.Schedule{}, err
+	}
+
+	routes, served := p.construct()
+	for vi := range routes {
+		routes[vi] = p.improve(ctx, &p.vehicles[vi], routes[vi])
+	}
+	p.insert(ctx, routes, served)
+	for vi := range routes {
+		routes[vi] = p.improve(ctx, &p.vehicles[vi], routes[vi])
+	}
+
+	return p.schedule(routes, served), nil
+}
+
+// provider returns the matrix provider of s.
+func (s *Solver) provider() routific.MatrixProvider {
+
+	if s.matrix != nil {
+		return s.matrix
+	}
+
+	return routific.Haversine{
+		Circuity: s.circuity,
+		Speed:    routific.ConstantSpeed(s.speed),
+	}
+}
+
+// SolvePDP implements routific.Solver. Pickup-and-delivery plans are not
+// supported, so it returns an error matching routific.ErrNotSupported.
+func (s *Solver) SolvePDP(
+	ctx context.Context,
+	plan routific.PDPlan,
+) (routific.Schedule, error) {
+	return routific.Schedule{}, routific.ErrNotSupported
+}
+
+// schedule converts solved routes into the Routific output format.
+func (p *problem) schedule(routes [][]int, served []bool) routific.Schedule {
+
+	out := routific.Schedule{
+		Status:   "success",
+		Solution: map[string]routific.Stops{},
+	}
+
+	var travel, idle, distance, working float64
+
+	for vi, route := range routes {
+		v := &p.vehicles[vi]
+		r, _ := p.simulate(v, route)
+
+		// Leave the depot late rather than wait at the first visit, unless
+		// a break comes first
+		if len(r.legs) > 0 && (len(r.breaks) == 0 || r.breaks[0].after > 0) {
+			wait := r.legs[0].start - r.legs[0].arrival
+			r.depart += wait
+			r.legs[0].arrival += wait
+			r.idle -= wait
+		}
+
+		start := p.nodes[v.start]
+		stops := routific.Stops{{
+			ID:          start.id,
+			Name:        start.loc.Name,
+			ArrivalTime: clock(r.depart),
+		}}
+
+		breaks := r.breaks
+		pause := func(after int) {
+			for len(breaks) > 0 && breaks[0].after == after {
+				stops = append(stops, routific.Stop{
+					ID:          breaks[0].id,
+					ArrivalTime: clock(breaks[0].start),
+					FinishTime:  clock(breaks[0].finish),
+					Break:       true,
+				})
+				breaks = breaks[1:]
+			}
+		}
+
+		at := v.start
+		for i, n := range route {
+			pause(i)
+			km := p.distance(at, n)
+			stops = append(stops, routific.Stop{
+				ID:          p.nodes[n].id,
+				Name:        p.nodes[n].loc.Name,
+				ArrivalTime: clock(r.legs[i].arrival),
+				FinishTime:  clock(r.legs[i].finish),
+				Distance:    round(km),
+				IdleTime:    round(r.legs[i].start - r.legs[i].arrival),
+			})
+			distance += km
+			at = n
+		}
+		pause(len(route))
+
+		if v.end >= 0 {
+			end := p.nodes[v.end]
+			km := p.distance(at, v.end)
+			stops = append(stops, routific.Stop{
+				ID:          end.id,
+				Name:        end.loc.Name,
+				ArrivalTime: clock(r.back),
+				Distance:    round(km),
+			})
+			distance += km
+		}
+
+		out.Solution[v.id] = stops
+		travel += r.travel
+		idle += r.idle
+		if len(route) > 0 {
+			working += r.back - r.depart
+		}
+	}
+
+	for n := 0; n < p.visits; n++ {
+		if served[n] {
+			continue
+		}
+		if out.Unserved == nil {
+			out.Unserved = map[string]string{}
+		}
+		out.Unserved[p.nodes[n].id] = p.reason(n)
+	}
+
+	out.NumUnserved = len(out.Unserved)
+	out.TravelTime = round(travel)
+	out.IdleTime = round(idle)
+	out.TotalDistance = round(distance)
+	out.WorkingTime = round(working)
+
+	return out
+}
+
+// round rounds x to two decimals for the output.
+func round(x float64) float32 {
+	return float32(math.Round(x*100) / 100)
+}
+
+// reason explains why visit n could not be served.
+func (p *problem) reason(n int) string {
+
+	typed, fits := false, false
+	for vi := range p.vehicles {
+		v := &p.vehicles[vi]
+		if p.nodes[n].vtype == "" || p.nodes[n].vtype == v.vtype {
+			typed = true
+			if p.nodes[n].load.Fits(v.capacity) {
+				fits = true
+			}
+		}
+	}
+
+	switch {
+	case !typed:
+		return "No vehicle of the required type"
+	case !fits:
+		return "Load exceeds the capacity of every vehicle"
+	}
+
+	return "Cannot be visited within the time windows and shifts"
+}
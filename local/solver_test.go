@@ -0,0 +1,354 @@
+package local_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/local"
+	"github.com/stretchr/testify/assert"
+)
+
+// solver_test checks that the offline solver keeps to the constraints of the
+// plan. Locations are in Vancouver, as in the Routific docs.
+
+var depot = r.Location{
+	ID:        "depot",
+	Name:      "800 Kingsway",
+	Latitude:  49.2553636,
+	Longitude: -123.0873365,
+}
+
+var visits = map[string]r.Visit{
+	"order_1": {Location: r.Location{
+		Name: "6800 Cambie", Latitude: 49.227107, Longitude: -123.1163085}},
+	"order_2": {Location: r.Location{
+		Name: "3780 Arbutus", Latitude: 49.2474624, Longitude: -123.1532338}},
+	"order_3": {Location: r.Location{
+		Name: "800 Robson", Latitude: 49.2819229, Longitude: -123.1211844}},
+}
+
+func TestSolveVRP(t *testing.T) {
+
+	plan := r.VRPlan{
+		Visits: visits,
+		Fleet: map[string]r.Vehicle{
+			"vehicle_1": {
+				StartLocation: depot,
+				EndLocation:   depot,
+				ShiftStart:    r.Clock(8, 0),
+				ShiftEnd:      r.Clock(17, 0),
+			},
+		},
+	}
+
+	schedule, err := local.NewSolver().SolveVRP(context.Background(), plan)
+	assert.Nil(t, err)
+	assert.Equal(t, "success", schedule.Status)
+	assert.Empty(t, schedule.Unserved)
+	assert.Greater(t, schedule.TravelTime, float32(0))
+
+	route := schedule.Solution["vehicle_1"]
+	assert.Len(t, route, 5)
+	assert.Equal(t, "depot", route[0].ID)
+	assert.Equal(t, r.Clock(8, 0), route[0].ArrivalTime)
+	assert.Equal(t, "depot", route[4].ID)
+
+	ids := map[string]bool{}
+	for _, stop := range route[1:4] {
+		ids[stop.ID] = true
+		assert.Equal(t, visits[stop.ID].Location.Name, stop.Name)
+	}
+	assert.Len(t, ids, 3)
+
+	var km float32
+	for _, stop := range route {
+		km += stop.Distance
+	}
+	assert.InDelta(t, schedule.TotalDistance, km, 0.05)
+	assert.GreaterOrEqual(t, schedule.WorkingTime, schedule.TravelTime)
+}
+
+func TestSolveVRPTimeWindows(t *testing.T) {
+
+	v := map[string]r.Visit{}
+	for id, visit := range visits {
+		v[id] = visit
+	}
+	late := v["order_1"]
+	late.Start, late.End, late.Duration = r.Clock(10, 0), r.Clock(10, 30), 15
+	v["order_1"] = late
+
+	early := v["order_3"]
+	early.Start, early.End = r.Clock(9, 0), r.Clock(9, 30)
+	v["order_3"] = early
+
+	plan := r.VRPlan{
+		Visits: v,
+		Fleet: map[string]r.Vehicle{
+			"vehicle_1": {
+				StartLocation: depot,
+				ShiftStart:    r.Clock(9, 0),
+				ShiftEnd:      r.Clock(12, 0),
+			},
+		},
+	}
+
+	schedule, err := local.NewSolver().SolveVRP(context.Background(), plan)
+	assert.Nil(t, err)
+	assert.Empty(t, schedule.Unserved)
+
+	route := schedule.Solution["vehicle_1"]
+	assert.Len(t, route, 4) // no end location
+	for _, stop := range route[1:] {
+		switch stop.ID {
+		case "order_1":
+			// Service starts in the window, possibly after waiting
+			assert.LessOrEqual(t, stop.ArrivalTime, r.Clock(10, 30))
+			assert.LessOrEqual(t, r.Clock(10, 15), stop.FinishTime)
+			assert.LessOrEqual(t, stop.FinishTime, r.Clock(10, 45))
+		case "order_3":
+			assert.LessOrEqual(t, stop.ArrivalTime, r.Clock(9, 30))
+		}
+	}
+}
+
+func TestSolveVRPUnserved(t *testing.T) {
+
+	v := map[string]r.Visit{}
+	for id, visit := range visits {
+		v[id] = visit
+	}
+	v["order_1"] = r.Visit{Location: v["order_1"].Location, Type: "truck"}
+	v["order_2"] = r.Visit{Location: v["order_2"].Location, Load: r.Units(5)}
+	v["order_3"] = r.Visit{
+		Location: v["order_3"].Location, Start: r.Clock(6, 0), End: r.Clock(7, 0)}
+
+	plan := r.VRPlan{
+		Visits: v,
+		Fleet: map[string]r.Vehicle{
+			"vehicle_1": {
+				StartLocation: depot,
+				EndLocation:   depot,
+				ShiftStart:    r.Clock(8, 0),
+				Capacity:      r.Units(2),
+			},
+		},
+	}
+
+	schedule, err := local.NewSolver().SolveVRP(context.Background(), plan)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, schedule.NumUnserved)
+	assert.Equal(t, map[string]string{
+		"order_1": "No vehicle of the required type",
+		"order_2": "Load exceeds the capacity of every vehicle",
+		"order_3": "Cannot be visited within the time windows and shifts",
+	}, schedule.Unserved)
+	assert.Len(t, schedule.Solution["vehicle_1"], 2)
+}
+
+func TestSolveVRPInvalidTime(t *testing.T) {
+
+	plan := r.VRPlan{
+		Visits: map[string]r.Visit{"order_1": {Start: r.Clock(25, 0)}},
+		Fleet:  map[string]r.Vehicle{"vehicle_1": {StartLocation: depot}},
+	}
+
+	_, err := local.NewSolver().SolveVRP(context.Background(), plan)
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+}
+
+func TestSolvePDP(t *testing.T) {
+
+	var s r.Solver = local.NewSolver()
+
+	_, err := s.SolvePDP(context.Background(), r.PDPlan{})
+	assert.True(t, errors.Is(err, r.ErrNotSupported))
+}
+
+func TestSolveVRPManyUnserved(t *testing.T) {
+
+	// More unserved visits than fit in a byte
+	v := map[string]r.Visit{}
+	for i := 0; i < 300; i++ {
+		v[fmt.Sprintf("order_%d", i)] = r.Visit{
+			Location: visits["order_1"].Location,
+			Duration: 240,
+			Type:     "truck",
+		}
+	}
+
+	plan := r.VRPlan{
+		Visits: v,
+		Fleet:  map[string]r.Vehicle{"vehicle_1": {StartLocation: depot}},
+	}
+
+	schedule, err := local.NewSolver().SolveVRP(context.Background(), plan)
+	assert.Nil(t, err)
+	assert.Equal(t, 300, schedule.NumUnserved)
+	assert.Len(t, schedule.Unserved, 300)
+}
+
+func TestSolveVRPBreaks(t *testing.T) {
+
+	v := map[string]r.Visit{}
+	for id, visit := range visits {
+		visit.Duration = 60
+		v[id] = visit
+	}
+
+	plan := r.VRPlan{
+		Visits: v,
+		Fleet: map[string]r.Vehicle{
+			"vehicle_1": {
+				StartLocation: depot,
+				EndLocation:   depot,
+				ShiftStart:    r.Clock(8, 0),
+				Breaks: []r.Break{{
+					ID:       "lunch",
+					Start:    r.Clock(9, 0),
+					End:      r.Clock(11, 0),
+					Duration: 30,
+				}},
+			},
+		},
+	}
+
+	schedule, err := local.NewSolver().SolveVRP(context.Background(), plan)
+	assert.Nil(t, err)
+	assert.Empty(t, schedule.Unserved)
+
+	route := schedule.Solution["vehicle_1"]
+	assert.Len(t, route, 6)
+
+	breaks := route.Breaks()
+	assert.Len(t, breaks, 1)
+	lunch := breaks[0]
+	assert.Equal(t, "lunch", lunch.ID)
+	assert.LessOrEqual(t, r.Clock(9, 0), lunch.ArrivalTime)
+	assert.LessOrEqual(t, lunch.ArrivalTime, r.Clock(10, 30))
+	assert.Equal(t, 30*time.Minute, lunch.FinishTime.Sub(lunch.ArrivalTime))
+
+	// No visit overlaps the break
+	for _, stop := range route[1:5] {
+		if !stop.Break {
+			assert.True(t, !stop.FinishTime.After(lunch.ArrivalTime) ||
+				!stop.ArrivalTime.Before(lunch.FinishTime), stop.ID)
+		}
+	}
+}
+
+func TestSolveVRPMatrix(t *testing.T) {
+
+	plan := r.VRPlan{
+		Visits: visits,
+		Fleet: map[string]r.Vehicle{
+			"vehicle_1": {StartLocation: depot, EndLocation: depot},
+		},
+	}
+
+	// Every leg takes 10 minutes over 5 km
+	var asked int
+	flat := r.MatrixFunc(func(_ context.Context, locs []r.Location) (r.Matrix, error) {
+		asked = len(locs)
+		m := r.NewMatrix(len(locs))
+		for i := range locs {
+			for j := range locs {
+				if i != j {
+					m.Durations[i][j], m.Distances[i][j] = 10, 5
+				}
+			}
+		}
+		return m, nil
+	})
+
+	schedule, err := local.NewSolver(local.WithMatrix(flat)).SolveVRP(
+		context.Background(), plan)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, asked) // visits and both depots
+	assert.Equal(t, float32(40), schedule.TravelTime)
+	assert.Equal(t, float32(20), schedule.TotalDistance)
+
+	failing := r.MatrixFunc(func(context.Context, []r.Location) (r.Matrix, error) {
+		return r.Matrix{}, r.ErrNotSupported
+	})
+	_, err = local.NewSolver(local.WithMatrix(failing)).SolveVRP(
+		context.Background(), plan)
+	assert.True(t, errors.Is(err, r.ErrNotSupported))
+}
+
+// manyVisits returns n visits spread over Vancouver, every other one with a
+// two-hour time window.
+func manyVisits(n int) map[string]r.Visit {
+
+	v := map[string]r.Visit{}
+	for i := 0; i < n; i++ {
+		visit := r.Visit{
+			Location: r.Location{
+				Latitude:  49.20 + float32(i*37%100)/1000,
+				Longitude: -123.20 + float32(i*61%150)/1000,
+			},
+			Duration: 5,
+		}
+		if i%2 == 0 {
+			visit.Start = r.Clock(8+i%8, 0)
+			visit.End = r.Clock(10+i%8, 0)
+		}
+		v[fmt.Sprintf("order_%d", i)] = visit
+	}
+
+	return v
+}
+
+func TestSolveVRPLarge(t *testing.T) {
+
+	plan := r.VRPlan{Visits: manyVisits(200), Fleet: map[string]r.Vehicle{}}
+	for i := 1; i <= 4; i++ {
+		plan.Fleet[fmt.Sprintf("vehicle_%d", i)] = r.Vehicle{
+			StartLocation: depot,
+			EndLocation:   depot,
+			ShiftStart:    r.Clock(7, 0),
+			ShiftEnd:      r.Clock(20, 0),
+		}
+	}
+
+	start := time.Now()
+	schedule, err := local.NewSolver().SolveVRP(context.Background(), plan)
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, 0, schedule.NumUnserved)
+
+	// Out of time, the routes found so far are returned
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	rushed, err := local.NewSolver().SolveVRP(ctx, plan)
+	assert.Nil(t, err)
+	assert.Equal(t, "success", rushed.Status)
+	assert.Equal(t, 0, rushed.NumUnserved)
+	assert.GreaterOrEqual(t, rushed.TravelTime, schedule.TravelTime)
+}
+
+func TestSolveVRPEndOfDay(t *testing.T) {
+
+	plan := r.VRPlan{
+		Visits: map[string]r.Visit{"order_1": {
+			Location: depot, Start: r.Clock(23, 50), Duration: 9.7}},
+		Fleet: map[string]r.Vehicle{"vehicle_1": {
+			StartLocation: depot, ShiftStart: r.Clock(23, 50)}},
+	}
+
+	schedule, err := local.NewSolver().SolveVRP(context.Background(), plan)
+	assert.Nil(t, err)
+	assert.Empty(t, schedule.Unserved)
+
+	// Finished at 23:59.7, not 24:00
+	route := schedule.Solution["vehicle_1"]
+	assert.Equal(t, r.Clock(23, 59), route[1].FinishTime)
+	_, err = json.Marshal(schedule)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,160 @@
+package routific
+
+import (
+	"context"
+	"math"
+)
+
+// Matrix holds the travel between locations: Durations[i][j] and
+// Distances[i][j] are from the i-th to the j-th location of a request.
+type Matrix struct {
+	Durations [][]float64 `json:"durations"` // minutes
+	Distances [][]float64 `json:"distances"` // km
+}
+
+// NewMatrix returns a Matrix of n locations filled with zeros.
+func NewMatrix(n int) Matrix {
+
+	m := Matrix{
+		Durations: make([][]float64, n),
+		Distances: make([][]float64, n),
+	}
+	for i := 0; i < n; i++ {
+		m.Durations[i] = make([]float64, n)
+		m.Distances[i] = make([]float64, n)
+	}
+
+	return m
+}
+
+// MatrixProvider computes travel times and distances between locations, for
+// computations over a plan without the Routific API.
+type MatrixProvider interface {
+	Matrix(ctx context.Context, locations []Location) (Matrix, error)
+}
+
+// MatrixFunc is a function that implements MatrixProvider.
+type MatrixFunc func(ctx context.Context, locations []Location) (Matrix, error)
+
+// Matrix calls f.
+func (f MatrixFunc) Matrix(ctx context.Context, locations []Location) (Matrix, error) {
+	return f(ctx, locations)
+}
+
+// SpeedBand is the average speed of trips up to a distance.
+type SpeedBand struct {
+	MaxDistance float64 // km; 0 means any distance
+	Speed       float64 // km/h
+}
+
+// SpeedProfile gives the average speed of a trip by its distance, e.g.
+// slower for short trips in town than for long trips on highways. Bands are
+// in increasing order of distance, and trips longer than every band use the
+// last one.
+type SpeedProfile []SpeedBand
+
+// ConstantSpeed returns the profile of trips at kmh whatever their distance.
+func ConstantSpeed(kmh float64) SpeedProfile {
+	return SpeedProfile{{Speed: kmh}}
+}
+
+// DefaultSpeedProfile returns a profile for mixed urban and highway driving:
+// 25 km/h up to 2 km, 40 km/h up to 10 km, 60 km/h up to 50 km, and 80 km/h
+// beyond.
+func DefaultSpeedProfile() SpeedProfile {
+	return SpeedProfile{
+		{MaxDistance: 2, Speed: 25},
+		{MaxDistance: 10, Speed: 40},
+		{MaxDistance: 50, Speed: 60},
+		{Speed: 80},
+	}
+}
+
+// Minutes returns the minutes needed to drive km, or 0 if p is empty.
+func (p SpeedProfile) Minutes(km float64) float64 {
+
+	if len(p) == 0 || km == 0 {
+		return 0
+	}
+
+	band := p[len(p)-1]
+	for _, b := range p {
+		if b.MaxDistance == 0 || km <= b.MaxDistance {
+			band = b
+			break
+		}
+	}
+
+	return km / band.Speed * 60
+}
+
+// Haversine estimates road travel from the great-circle distance between
+// locations, multiplied by Circuity, at the speeds of Speed.
+type Haversine struct {
+	Circuity float64 // ratio of road to straight-line distance, e.g. 1.3
+	Speed    SpeedProfile
+}
+
+// Matrix implements MatrixProvider. A Circuity of 0 or less counts as 1,
+// the straight-line distance.
+func (h Haversine) Matrix(ctx context.Context, locations []Location) (Matrix, error) {
+
+	circuity := h.Circuity
+	if circuity <= 0 {
+		circuity = 1
+	}
+
+	return pairwise(locations, func(a, b Location) float64 {
+		return GreatCircle(a, b) * circuity
+	}, h.Speed), nil
+}
+
+// Manhattan estimates road travel on a street grid aligned north-south and
+// east-west, from the sum of the north-south and east-west distances between
+// locations, at the speeds of Speed.
+type Manhattan struct {
+	Speed SpeedProfile
+}
+
+// Matrix implements MatrixProvider.
+func (m Manhattan) Matrix(ctx context.Context, locations []Location) (Matrix, error) {
+	return pairwise(locations, func(a, b Location) float64 {
+		corner := Location{Latitude: a.Latitude, Longitude: b.Longitude}
+		return GreatCircle(a, corner) + GreatCircle(corner, b)
+	}, m.Speed), nil
+}
+
+// pairwise computes the matrix of locations from the distance function.
+func pairwise(locations []Location, km func(a, b Location) float64, speed SpeedProfile) Matrix {
+
+	m := NewMatrix(len(locations))
+
+	for i, a := range locations {
+		for j, b := range locations {
+			if i == j {
+				continue
+			}
+			d := km(a, b)
+			m.Distances[i][j] = d
+			m.Durations[i][j] = speed.Minutes(d)
+		}
+	}
+
+	return m
+}
+
+// GreatCircle returns the great-circle distance between a and b in km.
+func GreatCircle(a, b Location) float64 {
+
+	const earthRadius = 6371.0 // km
+
+	lat1 := float64(a.Latitude) * math.Pi / 180
+	lat2 := float64(b.Latitude) * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := float64(b.Longitude-a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
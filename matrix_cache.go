@@ -0,0 +1,113 @@
+package routific
+
+import (
+	"context"
+	"sync"
+)
+
+// coords is the key of a location in a matrix cache: its coordinates.
+type coords [2]float32
+
+func coordsOf(loc Location) coords {
+	return coords{loc.Latitude, loc.Longitude}
+}
+
+// leg is the cached travel between two locations.
+type leg struct {
+	duration, distance float64
+}
+
+// CachingMatrix is a MatrixProvider that remembers the travel between every
+// pair of locations it has been asked about, by coordinates, and asks its
+// provider only about the locations of pairs it does not know yet. It is
+// safe for concurrent use.
+type CachingMatrix struct {
+	provider MatrixProvider
+
+	mu   sync.Mutex
+	legs map[[2]coords]leg
+}
+
+var _ MatrixProvider = (*CachingMatrix)(nil)
+
+// NewCachingMatrix returns a CachingMatrix of provider.
+func NewCachingMatrix(provider MatrixProvider) *CachingMatrix {
+	return &CachingMatrix{provider: provider, legs: map[[2]coords]leg{}}
+}
+
+// Matrix implements MatrixProvider.
+func (c *CachingMatrix) Matrix(ctx context.Context, locations []Location) (Matrix, error) {
+
+	if missing := c.missing(locations); len(missing) > 0 {
+		m, err := c.provider.Matrix(ctx, missing)
+		if err != nil {
+			return Matrix{}, err
+		}
+		c.store(missing, m)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m := NewMatrix(len(locations))
+	for i, a := range locations {
+		for j, b := range locations {
+			l := c.legs[[2]coords{coordsOf(a), coordsOf(b)}]
+			m.Durations[i][j], m.Distances[i][j] = l.duration, l.distance
+		}
+	}
+
+	return m, nil
+}
+
+// Len returns the number of pairs of locations in the cache.
+func (c *CachingMatrix) Len() int {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.legs)
+}
+
+// missing returns the distinct locations that are part of a pair that is not
+// in the cache.
+func (c *CachingMatrix) missing(locations []Location) []Location {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var missing []Location
+	seen := map[coords]bool{}
+
+	for _, a := range locations {
+		for _, b := range locations {
+			pa, pb := coordsOf(a), coordsOf(b)
+			if _, ok := c.legs[[2]coords{pa, pb}]; ok {
+				continue
+			}
+			for _, loc := range []Location{a, b} {
+				if p := coordsOf(loc); !seen[p] {
+					seen[p] = true
+					missing = append(missing, loc)
+				}
+			}
+		}
+	}
+
+	return missing
+}
+
+func (c *CachingMatrix) store(locations []Location, m Matrix) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, a := range locations {
+		for j, b := range locations {
+			c.legs[[2]coords{coordsOf(a), coordsOf(b)}] = leg{
+				duration: m.Durations[i][j],
+				distance: m.Distances[i][j],
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package routific
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MatrixFile is a MatrixProvider of a precomputed matrix, e.g. exported from
+// a routing engine. Requested locations are found in the matrix by their ID
+// if they have one, and otherwise by their coordinates.
+//
+// The JSON form of a matrix file is
+//
+//	{
+//	  "locations": [{"id": "depot", "lat": 49.25, "lng": -123.08}, ...],
+//	  "durations": [[0, 12.5, ...], ...],
+//	  "distances": [[0, 7.1, ...], ...]
+//	}
+//
+// with durations in minutes and distances in km.
+type MatrixFile struct {
+	Locations []Location  `json:"locations"`
+	Durations [][]float64 `json:"durations"` // minutes
+	Distances [][]float64 `json:"distances"` // km
+
+	byID     map[string]int
+	byCoords map[coords]int
+}
+
+var _ MatrixProvider = (*MatrixFile)(nil)
+
+// OpenMatrixFile reads the matrix file at path.
+func OpenMatrixFile(path string) (*MatrixFile, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadMatrixFile(f)
+}
+
+// ReadMatrixFile reads a matrix file from r. The error matches
+// ErrInvalidInput if the matrix does not match its locations.
+func ReadMatrixFile(r io.Reader) (*MatrixFile, error) {
+
+	var m MatrixFile
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: matrix file: %v", ErrInvalidInput, err)
+	}
+
+	n := len(m.Locations)
+	for name, rows := range map[string][][]float64{
+		"durations": m.Durations,
+		"distances": m.Distances,
+	} {
+		if len(rows) != n {
+			return nil, fmt.Errorf("%w: matrix file: %d rows of %s for %d locations",
+				ErrInvalidInput, len(rows), name, n)
+		}
+		for i, row := range rows {
+			if len(row) != n {
+				return nil, fmt.Errorf("%w: matrix file: row %d of %s has %d columns, want %d",
+					ErrInvalidInput, i, name, len(row), n)
+			}
+		}
+	}
+
+	m.byID = map[string]int{}
+	m.byCoords = map[coords]int{}
+	for i, loc := range m.Locations {
+		if loc.ID != "" {
+			m.byID[loc.ID] = i
+		}
+		m.byCoords[coordsOf(loc)] = i
+	}
+
+	return &m, nil
+}
+
+// WriteMatrixFile writes the matrix of locations as a matrix file.
+func WriteMatrixFile(w io.Writer, locations []Location, m Matrix) error {
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(MatrixFile{
+		Locations: locations,
+		Durations: m.Durations,
+		Distances: m.Distances,
+	})
+}
+
+// Matrix implements MatrixProvider. The error matches ErrInvalidInput if a
+// location is not in the file.
+func (f *MatrixFile) Matrix(ctx context.Context, locations []Location) (Matrix, error) {
+
+	index := make([]int, len(locations))
+	for i, loc := range locations {
+		k, ok := f.byID[loc.ID]
+		if loc.ID == "" || !ok {
+			k, ok = f.byCoords[coordsOf(loc)]
+		}
+		if !ok {
+			return Matrix{}, fmt.Errorf("%w: location %s (%g, %g) not in matrix file",
+				ErrInvalidInput, loc.ID, loc.Latitude, loc.Longitude)
+		}
+		index[i] = k
+	}
+
+	m := NewMatrix(len(locations))
+	for i, a := range index {
+		for j, b := range index {
+			m.Durations[i][j] = f.Durations[a][b]
+			m.Distances[i][j] = f.Distances[a][b]
+		}
+	}
+
+	return m, nil
+}
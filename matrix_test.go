@@ -0,0 +1,148 @@
+package routific_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// matrix_test checks the travel-time matrix providers.
+// Test data is defined in setup_test.
+
+var matrixLocations = []r.Location{kingswayDepot, cambie, arbutus, robson}
+
+func TestSpeedProfile(t *testing.T) {
+
+	p := r.DefaultSpeedProfile()
+	assert.InDelta(t, 2.4, p.Minutes(1), 1e-9)  // 25 km/h
+	assert.InDelta(t, 7.5, p.Minutes(5), 1e-9)  // 40 km/h
+	assert.InDelta(t, 20, p.Minutes(20), 1e-9)  // 60 km/h
+	assert.InDelta(t, 75, p.Minutes(100), 1e-9) // 80 km/h
+	assert.Equal(t, 0.0, p.Minutes(0))
+
+	assert.InDelta(t, 90, r.ConstantSpeed(40).Minutes(60), 1e-9)
+	assert.Equal(t, 0.0, r.SpeedProfile(nil).Minutes(10))
+}
+
+func TestHaversine(t *testing.T) {
+
+	// Kingsway depot to 6800 Cambie is about 3.7 km as the crow flies
+	assert.InDelta(t, 3.7, r.GreatCircle(kingswayDepot, cambie), 0.1)
+
+	h := r.Haversine{Circuity: 1.3, Speed: r.ConstantSpeed(40)}
+	m, err := h.Matrix(context.Background(), matrixLocations)
+	assert.Nil(t, err)
+	assert.Len(t, m.Durations, 4)
+
+	for i := range matrixLocations {
+		assert.Equal(t, 0.0, m.Durations[i][i])
+		for j := range matrixLocations {
+			assert.InDelta(t, m.Distances[i][j], m.Distances[j][i], 1e-9)
+			assert.InDelta(t, m.Distances[i][j]/40*60, m.Durations[i][j], 1e-9)
+		}
+	}
+	assert.InDelta(t, 1.3*r.GreatCircle(kingswayDepot, cambie), m.Distances[0][1], 1e-9)
+
+	// Without a circuity, distances are straight lines
+	m, err = r.Haversine{Speed: r.ConstantSpeed(40)}.Matrix(
+		context.Background(), matrixLocations)
+	assert.Nil(t, err)
+	assert.InDelta(t, r.GreatCircle(kingswayDepot, cambie), m.Distances[0][1], 1e-9)
+	assert.Greater(t, m.Durations[0][1], 0.0)
+}
+
+func TestManhattan(t *testing.T) {
+
+	m, err := r.Manhattan{Speed: r.ConstantSpeed(30)}.Matrix(
+		context.Background(), matrixLocations)
+	assert.Nil(t, err)
+
+	// Never shorter than the straight line, at most √2 times longer
+	for i, a := range matrixLocations {
+		for j, b := range matrixLocations {
+			d := r.GreatCircle(a, b)
+			assert.GreaterOrEqual(t, m.Distances[i][j], d-1e-9)
+			assert.LessOrEqual(t, m.Distances[i][j], d*1.4143)
+		}
+	}
+}
+
+func TestCachingMatrix(t *testing.T) {
+
+	var asked [][]r.Location
+	provider := r.MatrixFunc(func(ctx context.Context, locs []r.Location) (r.Matrix, error) {
+		asked = append(asked, locs)
+		return r.Haversine{Circuity: 1, Speed: r.ConstantSpeed(60)}.Matrix(ctx, locs)
+	})
+
+	c := r.NewCachingMatrix(provider)
+	ctx := context.Background()
+
+	want, _ := r.Haversine{Circuity: 1, Speed: r.ConstantSpeed(60)}.Matrix(ctx, matrixLocations)
+
+	m, err := c.Matrix(ctx, matrixLocations[:3])
+	assert.Nil(t, err)
+	assert.Equal(t, 9, c.Len())
+
+	// Only pairs with the new location are missing
+	m, err = c.Matrix(ctx, []r.Location{robson, kingswayDepot, cambie, arbutus})
+	assert.Nil(t, err)
+	assert.Equal(t, 16, c.Len())
+	assert.Len(t, asked, 2)
+	assert.Equal(t, []r.Location{robson, kingswayDepot, cambie, arbutus}, asked[1])
+	assert.Equal(t, want.Distances[3][0], m.Distances[0][1])
+
+	// All known
+	m, err = c.Matrix(ctx, matrixLocations)
+	assert.Nil(t, err)
+	assert.Len(t, asked, 2)
+	assert.Equal(t, want, m)
+
+	// Errors are not cached
+	failing := r.NewCachingMatrix(r.MatrixFunc(
+		func(context.Context, []r.Location) (r.Matrix, error) {
+			return r.Matrix{}, r.ErrNotSupported
+		}))
+	_, err = failing.Matrix(ctx, matrixLocations)
+	assert.True(t, errors.Is(err, r.ErrNotSupported))
+	assert.Equal(t, 0, failing.Len())
+}
+
+func TestMatrixFile(t *testing.T) {
+
+	ctx := context.Background()
+	want, _ := r.Manhattan{Speed: r.DefaultSpeedProfile()}.Matrix(ctx, matrixLocations)
+
+	var b bytes.Buffer
+	assert.Nil(t, r.WriteMatrixFile(&b, matrixLocations, want))
+
+	f, err := r.ReadMatrixFile(&b)
+	assert.Nil(t, err)
+
+	// By ID for the depot, by coordinates for the others
+	depot := r.Location{ID: "depot"}
+	m, err := f.Matrix(ctx, []r.Location{robson, depot})
+	assert.Nil(t, err)
+	assert.Equal(t, want.Durations[3][0], m.Durations[0][1])
+	assert.Equal(t, want.Distances[0][3], m.Distances[1][0])
+
+	_, err = f.Matrix(ctx, []r.Location{{ID: "elsewhere", Latitude: 1, Longitude: 1}})
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+
+	for _, s := range []string{
+		`{"locations": [{"lat": 1, "lng": 1}], "durations": [], "distances": [[0]]}`,
+		`{"locations": [{"lat": 1, "lng": 1}], "durations": [[0, 1]], "distances": [[0]]}`,
+		`not json`,
+	} {
+		_, err := r.ReadMatrixFile(strings.NewReader(s))
+		assert.True(t, errors.Is(err, r.ErrInvalidInput), s)
+	}
+
+	_, err = r.OpenMatrixFile("testdata/no-such-file.json")
+	assert.NotNil(t, err)
+}
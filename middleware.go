@@ -0,0 +1,207 @@
+package routific
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Middleware decorates a Solver with extra behaviour.
+type Middleware func(Solver) Solver
+
+// Chain wraps s in the given middleware. The first middleware is the
+// outermost, i.e. it sees a call first and its result last.
+func Chain(s Solver, mws ...Middleware) Solver {
+
+	for i := len(mws) - 1; i >= 0; i-- {
+		s = mws[i](s)
+	}
+
+	return s
+}
+
+// Logging logs every call with its duration and outcome.
+func Logging(logger *log.Logger) Middleware {
+	return Timing(func(method string, d time.Duration, err error) {
+		if err != nil {
+			logger.Printf("routific: %s failed after %s: %v", method, d, err)
+			return
+		}
+		logger.Printf("routific: %s took %s", method, d)
+	})
+}
+
+// Timing calls observe after every call with the name of the method
+// ("SolveVRP" or "SolvePDP"), its duration and its error, e.g. to record
+// metrics.
+func Timing(observe func(method string, d time.Duration, err error)) Middleware {
+	return func(next Solver) Solver {
+		return SolverFuncs{
+			VRP: func(ctx context.Context, plan VRPlan) (Schedule, error) {
+				start := time.Now()
+				s, err := next.SolveVRP(ctx, plan)
+				observe("SolveVRP", time.Since(start), err)
+				return s, err
+			},
+			PDP: func(ctx context.Context, plan PDPlan) (Schedule, error) {
+				start := time.Now()
+				s, err := next.SolvePDP(ctx, plan)
+				observe("SolvePDP", time.Since(start), err)
+				return s, err
+			},
+		}
+	}
+}
+
+// Fallback retries failed calls with secondary, e.g. a local.Solver when the
+// API is unavailable. Calls failing because their context is done are not
+// retried.
+func Fallback(secondary Solver) Middleware {
+	return func(next Solver) Solver {
+		return SolverFuncs{
+			VRP: func(ctx context.Context, plan VRPlan) (Schedule, error) {
+				s, err := next.SolveVRP(ctx, plan)
+				if err == nil || ctx.Err() != nil {
+					return s, err
+				}
+				return secondary.SolveVRP(ctx, plan)
+			},
+			PDP: func(ctx context.Context, plan PDPlan) (Schedule, error) {
+				s, err := next.SolvePDP(ctx, plan)
+				if err == nil || ctx.Err() != nil {
+					return s, err
+				}
+				return secondary.SolvePDP(ctx, plan)
+			},
+		}
+	}
+}
+
+// Cache stores schedules by key. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	Get(key string) (Schedule, bool)
+	Set(key string, s Schedule)
+}
+
+// Caching returns the schedule cached for an identical plan, if any, and
+// caches the schedules of successful calls otherwise.
+func Caching(cache Cache) Middleware {
+	return func(next Solver) Solver {
+		return SolverFuncs{
+			VRP: func(ctx context.Context, plan VRPlan) (Schedule, error) {
+				return cached(cache, "vrp", plan, func() (Schedule, error) {
+					return next.SolveVRP(ctx, plan)
+				})
+			},
+			PDP: func(ctx context.Context, plan PDPlan) (Schedule, error) {
+				return cached(cache, "pdp", plan, func() (Schedule, error) {
+					return next.SolvePDP(ctx, plan)
+				})
+			},
+		}
+	}
+}
+
+func cached(
+	cache Cache,
+	kind string,
+	plan interface{},
+	solve func() (Schedule, error),
+) (Schedule, error) {
+
+	key, err := planKey(kind, plan)
+	if err != nil {
+		return solve()
+	}
+
+	if s, ok := cache.Get(key); ok {
+		return s, nil
+	}
+
+	s, err := solve()
+	if err == nil {
+		cache.Set(key, s)
+	}
+
+	return s, err
+}
+
+// planKey identifies a plan by the hash of its JSON encoding, which is
+// stable because encoding/json sorts map keys.
+func planKey(kind string, plan interface{}) (string, error) {
+
+	v, err := json.Marshal(plan)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(v)
+	return kind + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// MemoryCache is an in-memory Cache whose entries expire after a given time.
+// It keeps schedules JSON-encoded, so that callers changing the schedules
+// they set or get do not change the cached ones.
+type MemoryCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	schedule []byte // JSON
+	expires  time.Time
+}
+
+// NewMemoryCache returns an empty MemoryCache whose entries expire after
+// ttl. Zero means they never expire.
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{ttl: ttl, entries: map[string]cacheEntry{}}
+}
+
+// Get implements Cache.
+func (c *MemoryCache) Get(key string) (Schedule, bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return Schedule{}, false
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return Schedule{}, false
+	}
+
+	var s Schedule
+	if err := json.Unmarshal(e.schedule, &s); err != nil {
+		return Schedule{}, false
+	}
+
+	return s, true
+}
+
+// Set implements Cache. Schedules that cannot be encoded, e.g. with invalid
+// times, are not cached.
+func (c *MemoryCache) Set(key string, s Schedule) {
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := cacheEntry{schedule: b}
+	if c.ttl > 0 {
+		e.expires = time.Now().Add(c.ttl)
+	}
+	c.entries[key] = e
+}
@@ -0,0 +1,134 @@
+package routific_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// middleware_test checks the Solver decorators against fake solvers.
+// Test data is defined in setup_test.
+
+// fakeSolver counts its calls and returns the given schedule and error.
+func fakeSolver(s r.Schedule, err error, calls *int) r.Solver {
+	return r.SolverFuncs{
+		VRP: func(ctx context.Context, plan r.VRPlan) (r.Schedule, error) {
+			*calls++
+			return s, err
+		},
+		PDP: func(ctx context.Context, plan r.PDPlan) (r.Schedule, error) {
+			*calls++
+			return s, err
+		},
+	}
+}
+
+func TestMiddlewareCaching(t *testing.T) {
+
+	calls := 0
+	s := r.Chain(fakeSolver(vrpOutput, nil, &calls),
+		r.Caching(r.NewMemoryCache(time.Minute)))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		output, err := s.SolveVRP(ctx, vrpInput)
+		assert.Nil(t, err)
+		assert.Equal(t, vrpOutput, output)
+	}
+	assert.Equal(t, 1, calls)
+
+	// A different plan, or kind of plan, is not served from the cache
+	_, err := s.SolveVRP(ctx, optionsInput)
+	assert.Nil(t, err)
+	_, err = s.SolvePDP(ctx, pdpInput)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestMemoryCacheCopies(t *testing.T) {
+
+	cache := r.NewMemoryCache(0)
+	s := r.Schedule{Solution: map[string]r.Stops{
+		"vehicle_1": {{ID: "depot", ArrivalTime: r.Clock(8, 0)}},
+	}}
+	cache.Set("key", s)
+
+	// Changing the schedule set or got leaves the cached one as it was
+	s.Solution["vehicle_1"][0].ID = "changed"
+	got, ok := cache.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "depot", got.Solution["vehicle_1"][0].ID)
+
+	got.Solution["vehicle_2"] = r.Stops{}
+	got, _ = cache.Get("key")
+	assert.Len(t, got.Solution, 1)
+}
+
+func TestMiddlewareCachingErrors(t *testing.T) {
+
+	calls := 0
+	s := r.Chain(fakeSolver(r.Schedule{}, r.ErrRateLimited, &calls),
+		r.Caching(r.NewMemoryCache(0)))
+
+	for i := 0; i < 2; i++ {
+		_, err := s.SolvePDP(context.Background(), pdpInput)
+		assert.True(t, errors.Is(err, r.ErrRateLimited))
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestMiddlewareFallback(t *testing.T) {
+
+	primaryCalls, secondaryCalls := 0, 0
+	s := r.Chain(fakeSolver(r.Schedule{}, r.ErrUnauthorized, &primaryCalls),
+		r.Fallback(fakeSolver(pdpOutput, nil, &secondaryCalls)))
+
+	output, err := s.SolvePDP(context.Background(), pdpInput)
+	assert.Nil(t, err)
+	assert.Equal(t, pdpOutput, output)
+	assert.Equal(t, 1, primaryCalls)
+	assert.Equal(t, 1, secondaryCalls)
+
+	// No fallback once the caller gave up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.SolvePDP(ctx, pdpInput)
+	assert.True(t, errors.Is(err, r.ErrUnauthorized))
+	assert.Equal(t, 1, secondaryCalls)
+}
+
+func TestMiddlewareChainOrder(t *testing.T) {
+
+	var logs bytes.Buffer
+	var methods []string
+
+	calls := 0
+	s := r.Chain(fakeSolver(vrpOutput, nil, &calls),
+		r.Logging(log.New(&logs, "", 0)),
+		r.Timing(func(method string, d time.Duration, err error) {
+			methods = append(methods, method)
+		}),
+		r.Caching(r.NewMemoryCache(0)),
+	)
+
+	ctx := context.Background()
+	s.SolveVRP(ctx, vrpInput)
+	s.SolveVRP(ctx, vrpInput)
+
+	// Timing is outside the cache, so it sees both calls
+	assert.Equal(t, []string{"SolveVRP", "SolveVRP"}, methods)
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, logs.String(), "routific: SolveVRP took")
+}
+
+func TestSolverFuncsNotSupported(t *testing.T) {
+
+	_, err := r.SolverFuncs{}.SolveVRP(context.Background(), vrpInput)
+	assert.True(t, errors.Is(err, r.ErrNotSupported))
+}
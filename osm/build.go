@@ -0,0 +1,191 @@
+package osm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/slamethendry/routific"
+)
+
+// builder collects the nodes and drivable ways of an extract, in any order,
+// and builds their Graph.
+type builder struct {
+	config
+	coords map[int64][2]float64 // lat, lng by OSM node ID
+	ways   []way
+}
+
+// way is a drivable way.
+type way struct {
+	refs    []int64 // OSM node IDs
+	speed   float64 // km/h
+	forward bool    // may be driven in the order of refs
+	reverse bool    // may be driven against the order of refs
+}
+
+func newBuilder(opts []Option) *builder {
+
+	b := &builder{
+		config: config{speeds: DefaultSpeeds(), maxSnap: 1},
+		coords: map[int64][2]float64{},
+	}
+	for _, opt := range opts {
+		opt(&b.config)
+	}
+
+	return b
+}
+
+// node adds a node of the extract.
+func (b *builder) node(id int64, lat, lng float64) {
+	b.coords[id] = [2]float64{lat, lng}
+}
+
+// way adds a way of the extract if it is drivable.
+func (b *builder) way(refs []int64, tags map[string]string) {
+
+	speed, ok := b.speeds[tags["highway"]]
+	if !ok || speed <= 0 || len(refs) < 2 {
+		return
+	}
+	switch tags["access"] {
+	case "no", "private":
+		return
+	}
+	if limit, ok := maxSpeed(tags["maxspeed"]); ok {
+		speed = limit
+	}
+
+	w := way{refs: refs, speed: speed, forward: true, reverse: true}
+	switch tags["oneway"] {
+	case "yes", "true", "1":
+		w.reverse = false
+	case "-1", "reverse":
+		w.forward = false
+	case "no", "false", "0":
+	default:
+		if tags["highway"] == "motorway" || tags["junction"] == "roundabout" {
+			w.reverse = false
+		}
+	}
+
+	b.ways = append(b.ways, w)
+}
+
+// maxSpeed parses a maxspeed tag in km/h, e.g. "50" or "30 mph".
+func maxSpeed(tag string) (float64, bool) {
+
+	tag = strings.TrimSpace(tag)
+	factor := 1.0
+	if strings.HasSuffix(tag, "mph") {
+		tag, factor = strings.TrimSpace(strings.TrimSuffix(tag, "mph")), 1.609344
+	}
+
+	v, err := strconv.ParseFloat(tag, 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+
+	return v * factor, true
+}
+
+// graph builds the Graph of the ways. Ways are cut where their nodes are
+// missing from the extract, and only the largest connected network is used
+// for snapping, so that locations are not snapped to isolated roads.
+func (b *builder) graph() (*Graph, error) {
+
+	g := &Graph{grid: map[cell][]int32{}, maxSnap: b.maxSnap}
+	index := map[int64]int32{}
+
+	type edge struct {
+		from, to int32
+		km       float64
+		speed    float64
+	}
+	var edges []edge
+
+	for _, w := range b.ways {
+		prev := int32(-1)
+		for _, ref := range w.refs {
+			c, ok := b.coords[ref]
+			if !ok {
+				prev = -1
+				continue
+			}
+			n, ok := index[ref]
+			if !ok {
+				n = int32(len(g.lat))
+				index[ref] = n
+				g.lat = append(g.lat, c[0])
+				g.lng = append(g.lng, c[1])
+			}
+			if prev >= 0 && prev != n {
+				km := distance(g.lat[prev], g.lng[prev], c[0], c[1])
+				if w.forward {
+					edges = append(edges, edge{prev, n, km, w.speed})
+				}
+				if w.reverse {
+					edges = append(edges, edge{n, prev, km, w.speed})
+				}
+			}
+			prev = n
+		}
+	}
+
+	if len(edges) == 0 {
+		return nil, fmt.Errorf("%w: osm: no drivable roads", routific.ErrInvalidInput)
+	}
+
+	// Edges by their start node
+	g.first = make([]int32, len(g.lat)+1)
+	for _, e := range edges {
+		g.first[e.from+1]++
+	}
+	for i := 1; i < len(g.first); i++ {
+		g.first[i] += g.first[i-1]
+	}
+	g.to = make([]int32, len(edges))
+	g.km = make([]float64, len(edges))
+	g.minutes = make([]float64, len(edges))
+	next := append([]int32(nil), g.first[:len(g.lat)]...)
+	for _, e := range edges {
+		k := next[e.from]
+		next[e.from]++
+		g.to[k], g.km[k], g.minutes[k] = e.to, e.km, e.km/e.speed*60
+	}
+
+	// Largest network, ignoring the direction of edges
+	parent := make([]int32, len(g.lat))
+	for i := range parent {
+		parent[i] = int32(i)
+	}
+	root := func(n int32) int32 {
+		for parent[n] != n {
+			parent[n] = parent[parent[n]]
+			n = parent[n]
+		}
+		return n
+	}
+	for _, e := range edges {
+		parent[root(e.from)] = root(e.to)
+	}
+	size := map[int32]int{}
+	largest := int32(0)
+	for n := range parent {
+		r := root(int32(n))
+		size[r]++
+		if size[r] > size[largest] {
+			largest = r
+		}
+	}
+
+	for n := range g.lat {
+		if root(int32(n)) == largest {
+			c := cellOf(g.lat[n], g.lng[n])
+			g.grid[c] = append(g.grid[c], int32(n))
+		}
+	}
+
+	return g, nil
+}
@@ -0,0 +1,196 @@
+// Package osm computes road travel times and distances offline from an
+// OpenStreetMap extract, as downloaded from Geofabrik or BBBike.
+//
+// A Graph is built from the drivable ways of an extract in the PBF or XML
+// format, each driven at the speed of its highway class, or at its maxspeed
+// tag. Locations are snapped to the nearest road node, and a Graph is a
+// routific.MatrixProvider that finds the fastest route between them with
+// Dijkstra's algorithm, e.g. for the offline solver:
+//
+//	g, err := osm.Open("british-columbia-latest.osm.pbf")
+//	solver := local.NewSolver(local.WithMatrix(routific.NewCachingMatrix(g)))
+//
+// Turn restrictions, traffic and ferries are ignored.
+package osm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/slamethendry/routific"
+)
+
+// accessSpeed is the speed in km/h between a location and its road node.
+const accessSpeed = 20.0
+
+// Graph is the road network of an OpenStreetMap extract. It is safe for
+// concurrent use.
+type Graph struct {
+	lat, lng []float64 // of the nodes
+
+	// Edges leaving node i are first[i] to first[i+1]
+	first   []int32
+	to      []int32
+	km      []float64
+	minutes []float64
+
+	grid    map[cell][]int32 // nodes of the largest connected network
+	maxSnap float64          // km
+}
+
+var _ routific.MatrixProvider = (*Graph)(nil)
+
+// config holds the options of reading a Graph.
+type config struct {
+	speeds  map[string]float64
+	maxSnap float64
+}
+
+// Option configures how a Graph is read.
+type Option func(*config)
+
+// WithSpeeds sets the speed in km/h of each highway class, e.g.
+// "residential". Ways of classes missing from speeds are not driven. The
+// default is DefaultSpeeds.
+func WithSpeeds(speeds map[string]float64) Option {
+	return func(c *config) {
+		c.speeds = speeds
+	}
+}
+
+// WithMaxSnap sets the greatest distance in km between a location and the
+// road node it is snapped to. The default is 1.
+func WithMaxSnap(km float64) Option {
+	return func(c *config) {
+		c.maxSnap = km
+	}
+}
+
+// DefaultSpeeds returns typical urban speeds in km/h of the drivable highway
+// classes.
+func DefaultSpeeds() map[string]float64 {
+	return map[string]float64{
+		"motorway":       100,
+		"motorway_link":  60,
+		"trunk":          80,
+		"trunk_link":     50,
+		"primary":        60,
+		"primary_link":   40,
+		"secondary":      50,
+		"secondary_link": 40,
+		"tertiary":       40,
+		"tertiary_link":  30,
+		"unclassified":   30,
+		"residential":    25,
+		"living_street":  10,
+		"service":        15,
+	}
+}
+
+// Open reads the extract at path, in the PBF format if its name ends in
+// ".pbf", and in the XML format otherwise.
+func Open(path string, opts ...Option) (*Graph, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(path, ".pbf") {
+		return ReadPBF(f, opts...)
+	}
+
+	return ReadXML(f, opts...)
+}
+
+// Nodes returns the number of road nodes of g.
+func (g *Graph) Nodes() int {
+	return len(g.lat)
+}
+
+// Snap returns loc moved to its nearest road node, keeping its ID and name.
+// The error matches routific.ErrInvalidInput if no road is near.
+func (g *Graph) Snap(loc routific.Location) (routific.Location, error) {
+
+	n, _, err := g.snap(loc)
+	if err != nil {
+		return routific.Location{}, err
+	}
+
+	loc.Latitude = float32(g.lat[n])
+	loc.Longitude = float32(g.lng[n])
+	return loc, nil
+}
+
+// snap returns the nearest road node of loc and its distance in km.
+func (g *Graph) snap(loc routific.Location) (int32, float64, error) {
+
+	n, km := g.nearest(float64(loc.Latitude), float64(loc.Longitude))
+	if n < 0 {
+		return 0, 0, fmt.Errorf("%w: location %s (%g, %g) is not within %g km of a road",
+			routific.ErrInvalidInput, loc.ID, loc.Latitude, loc.Longitude, g.maxSnap)
+	}
+
+	return n, km, nil
+}
+
+// Matrix implements routific.MatrixProvider. Travel from a location to its
+// road node and back is added at 20 km/h. The error matches
+// routific.ErrInvalidInput if a location is not near a road, or cannot be
+// reached from another one, e.g. because of one-way streets.
+func (g *Graph) Matrix(ctx context.Context, locations []routific.Location) (routific.Matrix, error) {
+
+	nodes := make([]int32, len(locations))
+	access := make([]float64, len(locations)) // km
+	for i, loc := range locations {
+		n, km, err := g.snap(loc)
+		if err != nil {
+			return routific.Matrix{}, err
+		}
+		nodes[i], access[i] = n, km
+	}
+
+	m := routific.NewMatrix(len(locations))
+	s := g.newSearch()
+	done := map[int32]int{} // row of each source node searched
+
+	for i := range locations {
+		if err := ctx.Err(); err != nil {
+			return routific.Matrix{}, err
+		}
+
+		if row, ok := done[nodes[i]]; ok {
+			copy(m.Durations[i], m.Durations[row])
+			copy(m.Distances[i], m.Distances[row])
+		} else {
+			s.run(nodes[i], nodes)
+			for j, n := range nodes {
+				if !s.reached(n) {
+					return routific.Matrix{}, fmt.Errorf(
+						"%w: location %s cannot be reached from %s by road",
+						routific.ErrInvalidInput, locations[j].ID, locations[i].ID)
+				}
+				m.Durations[i][j] = s.minutes[n]
+				m.Distances[i][j] = s.km[n]
+			}
+			done[nodes[i]] = i
+		}
+	}
+
+	for i := range locations {
+		for j := range locations {
+			if i == j {
+				m.Durations[i][j], m.Distances[i][j] = 0, 0
+				continue
+			}
+			km := access[i] + access[j]
+			m.Distances[i][j] += km
+			m.Durations[i][j] += km / accessSpeed * 60
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,379 @@
+package osm_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/osm"
+	"github.com/stretchr/testify/assert"
+)
+
+// osm_test reads a small network in both formats: a one-way residential
+// street from node 1 through node 2 to node 3, eastbound, and a two-way
+// primary road around it through nodes 4 and 5. Node 7 is on a footway,
+// and node 8 on a separate driveway.
+
+var nodes = map[int64]r.Location{
+	1: {Latitude: 49.25, Longitude: -123.10},
+	2: {Latitude: 49.25, Longitude: -123.09},
+	3: {Latitude: 49.25, Longitude: -123.08},
+	4: {Latitude: 49.26, Longitude: -123.10},
+	5: {Latitude: 49.26, Longitude: -123.08},
+	6: {Latitude: 49.24, Longitude: -123.09},
+	7: {Latitude: 49.245, Longitude: -123.09},
+	8: {Latitude: 49.262, Longitude: -123.078},
+	9: {Latitude: 49.263, Longitude: -123.078},
+}
+
+type testWay struct {
+	refs []int64
+	tags map[string]string
+}
+
+var ways = []testWay{
+	{[]int64{1, 2, 3}, map[string]string{"highway": "residential", "oneway": "yes"}},
+	{[]int64{1, 4}, map[string]string{"highway": "primary"}},
+	{[]int64{4, 5, 3}, map[string]string{"highway": "primary", "name": "Loop"}},
+	{[]int64{2, 7, 6}, map[string]string{"highway": "footway"}},
+	{[]int64{8, 9}, map[string]string{"highway": "service"}},
+}
+
+var speeds = osm.WithSpeeds(map[string]float64{
+	"residential": 30,
+	"primary":     60,
+	"service":     15,
+})
+
+const testXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="test">
+  <bounds minlat="49.24" minlon="-123.10" maxlat="49.27" maxlon="-123.07"/>
+  <node id="1" lat="49.25" lon="-123.10"/>
+  <node id="2" lat="49.25" lon="-123.09"/>
+  <node id="3" lat="49.25" lon="-123.08"><tag k="highway" v="traffic_signals"/></node>
+  <node id="4" lat="49.26" lon="-123.10"/>
+  <node id="5" lat="49.26" lon="-123.08"/>
+  <node id="6" lat="49.24" lon="-123.09"/>
+  <node id="7" lat="49.245" lon="-123.09"/>
+  <node id="8" lat="49.262" lon="-123.078"/>
+  <node id="9" lat="49.263" lon="-123.078"/>
+  <way id="10">
+    <nd ref="1"/><nd ref="2"/><nd ref="3"/>
+    <tag k="highway" v="residential"/><tag k="oneway" v="yes"/>
+  </way>
+  <way id="11"><nd ref="1"/><nd ref="4"/><tag k="highway" v="primary"/></way>
+  <way id="12">
+    <nd ref="4"/><nd ref="5"/><nd ref="3"/>
+    <tag k="highway" v="primary"/><tag k="name" v="Loop"/>
+  </way>
+  <way id="13">
+    <nd ref="2"/><nd ref="7"/><nd ref="6"/><tag k="highway" v="footway"/>
+  </way>
+  <way id="14"><nd ref="8"/><nd ref="9"/><tag k="highway" v="service"/></way>
+  <relation id="20"><member type="way" ref="12" role=""/><tag k="type" v="route"/></relation>
+</osm>`
+
+// path returns the length in km of the path through the nodes.
+func path(ids ...int64) float64 {
+
+	var km float64
+	for i := 1; i < len(ids); i++ {
+		km += r.GreatCircle(nodes[ids[i-1]], nodes[ids[i]])
+	}
+
+	return km
+}
+
+func TestReadXML(t *testing.T) {
+
+	g, err := osm.ReadXML(strings.NewReader(testXML), speeds)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, g.Nodes()) // not 6 and 7 of the footway
+	checkGraph(t, g)
+}
+
+func TestReadPBF(t *testing.T) {
+
+	g, err := osm.ReadPBF(bytes.NewReader(testPBF(true)), speeds)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, g.Nodes())
+	checkGraph(t, g)
+
+	g, err = osm.ReadPBF(bytes.NewReader(testPBF(false)), speeds)
+	assert.Nil(t, err)
+	checkGraph(t, g)
+
+	// Truncated
+	_, err = osm.ReadPBF(bytes.NewReader(testPBF(true)[:50]), speeds)
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+}
+
+func checkGraph(t *testing.T, g *osm.Graph) {
+
+	t.Helper()
+	ctx := context.Background()
+
+	west := r.Location{ID: "west", Latitude: 49.2501, Longitude: -123.1001}
+	east := r.Location{ID: "east", Latitude: 49.25, Longitude: -123.08}
+
+	snapped, err := g.Snap(west)
+	assert.Nil(t, err)
+	assert.Equal(t, "west", snapped.ID)
+	assert.Equal(t, nodes[1].Latitude, snapped.Latitude)
+	assert.Equal(t, nodes[1].Longitude, snapped.Longitude)
+
+	m, err := g.Matrix(ctx, []r.Location{west, east, west})
+	assert.Nil(t, err)
+
+	access := r.GreatCircle(west, nodes[1])
+
+	// East along the one-way street, back west around the loop
+	assert.InDelta(t, path(1, 2, 3)+access, m.Distances[0][1], 0.01)
+	assert.InDelta(t, path(1, 2, 3)/30*60+access/20*60, m.Durations[0][1], 0.01)
+	assert.InDelta(t, path(3, 5, 4, 1)+access, m.Distances[1][0], 0.01)
+	assert.InDelta(t, path(3, 5, 4, 1)/60*60+access/20*60, m.Durations[1][0], 0.01)
+
+	assert.Equal(t, 0.0, m.Durations[0][0])
+	assert.Equal(t, m.Durations[0][1], m.Durations[2][1])
+	assert.InDelta(t, 2*access, m.Distances[0][2], 0.001)
+
+	// The footway is not driven: node 7 snaps to the street
+	snapped, err = g.Snap(nodes[7])
+	assert.Nil(t, err)
+	assert.Equal(t, nodes[2], snapped)
+
+	// The driveway is not connected, so it is not snapped to
+	snapped, err = g.Snap(nodes[8])
+	assert.Nil(t, err)
+	assert.Equal(t, nodes[5], snapped)
+
+	_, err = g.Matrix(ctx, []r.Location{west, {ID: "far", Latitude: 49.5, Longitude: -123}})
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = g.Matrix(cancelled, []r.Location{west, east})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestSnapPole(t *testing.T) {
+
+	g, err := osm.ReadXML(strings.NewReader(testXML), speeds)
+	assert.Nil(t, err)
+
+	for _, lat := range []float32{90, -90} {
+		_, err = g.Snap(r.Location{ID: "pole", Latitude: lat})
+		assert.True(t, errors.Is(err, r.ErrInvalidInput), lat)
+	}
+}
+
+func TestReadOneway(t *testing.T) {
+
+	for _, c := range []struct {
+		tags             map[string]string
+		forward, reverse bool
+	}{
+		{map[string]string{"highway": "tertiary"}, true, true},
+		{map[string]string{"highway": "tertiary", "oneway": "-1"}, false, true},
+		{map[string]string{"highway": "tertiary", "junction": "roundabout"}, true, false},
+		{map[string]string{"highway": "motorway"}, true, false},
+		{map[string]string{"highway": "motorway", "oneway": "no"}, true, true},
+	} {
+		xml := `<osm>
+  <node id="1" lat="49.25" lon="-123.10"/>
+  <node id="2" lat="49.25" lon="-123.09"/>
+  <way id="3"><nd ref="1"/><nd ref="2"/>`
+		for k, v := range c.tags {
+			xml += `<tag k="` + k + `" v="` + v + `"/>`
+		}
+		xml += `</way></osm>`
+
+		g, err := osm.ReadXML(strings.NewReader(xml))
+		assert.Nil(t, err)
+
+		_, err = g.Matrix(context.Background(), []r.Location{nodes[1], nodes[2]})
+		assert.Equal(t, c.forward && c.reverse, err == nil, c.tags)
+		if err != nil {
+			assert.True(t, errors.Is(err, r.ErrInvalidInput))
+		}
+	}
+}
+
+func TestReadMaxSpeed(t *testing.T) {
+
+	for tag, kmh := range map[string]float64{"": 40, "50": 50, "20 mph": 32.18688} {
+		xml := `<osm>
+  <node id="1" lat="49.25" lon="-123.10"/>
+  <node id="2" lat="49.25" lon="-123.09"/>
+  <way id="3"><nd ref="1"/><nd ref="2"/>
+    <tag k="highway" v="tertiary"/><tag k="maxspeed" v="` + tag + `"/>
+  </way></osm>`
+
+		g, err := osm.ReadXML(strings.NewReader(xml))
+		assert.Nil(t, err)
+
+		m, err := g.Matrix(context.Background(), []r.Location{nodes[1], nodes[2]})
+		assert.Nil(t, err)
+		assert.InDelta(t, path(1, 2)/kmh*60, m.Durations[0][1], 0.001, tag)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+
+	for _, s := range []string{
+		`<osm><node id="1" lat="49.25" lon="-123.10"/></osm>`,
+		`<osm><node id="1" lat="north"/></osm>`,
+		`<osm><way id="3">`,
+	} {
+		_, err := osm.ReadXML(strings.NewReader(s))
+		assert.True(t, errors.Is(err, r.ErrInvalidInput), s)
+	}
+
+	_, err := osm.Open("testdata/no-such-file.osm.pbf")
+	assert.NotNil(t, err)
+}
+
+// testPBF encodes the test network in the PBF format, with dense nodes and
+// compressed blobs or with plain nodes and raw blobs.
+func testPBF(dense bool) []byte {
+
+	var file bytes.Buffer
+
+	blob := func(kind string, data []byte) {
+		var b []byte
+		if dense {
+			var z bytes.Buffer
+			w := zlib.NewWriter(&z)
+			w.Write(data)
+			w.Close()
+			b = appendVarint(b, 2, uint64(len(data)))
+			b = appendBytes(b, 3, z.Bytes())
+		} else {
+			b = appendBytes(b, 1, data)
+		}
+
+		var header []byte
+		header = appendBytes(header, 1, []byte(kind))
+		header = appendVarint(header, 3, uint64(len(b)))
+
+		binary.Write(&file, binary.BigEndian, uint32(len(header)))
+		file.Write(header)
+		file.Write(b)
+	}
+
+	var header []byte
+	header = appendBytes(header, 4, []byte("OsmSchema-V0.6"))
+	header = appendBytes(header, 4, []byte("DenseNodes"))
+	blob("OSMHeader", header)
+
+	// Coordinates in units of 100 nanodegrees from an offset
+	const granularity = 100
+	latOffset, lngOffset := int64(49e9), int64(-123e9)
+	coord := func(deg float32, offset int64) int64 {
+		return (int64(float64(deg)*1e7+0.5*sign(deg))*100 - offset) / granularity
+	}
+
+	var group []byte
+	if dense {
+		var ids, lats, lngs []byte
+		var id, lat, lng int64
+		for n := int64(1); n <= 9; n++ {
+			la := coord(nodes[n].Latitude, latOffset)
+			ln := coord(nodes[n].Longitude, lngOffset)
+			ids = uvarint(ids, zig(n-id))
+			lats = uvarint(lats, zig(la-lat))
+			lngs = uvarint(lngs, zig(ln-lng))
+			id, lat, lng = n, la, ln
+		}
+		var d []byte
+		d = appendBytes(d, 1, ids)
+		d = appendBytes(d, 8, lats)
+		d = appendBytes(d, 9, lngs)
+		group = appendBytes(group, 2, d)
+	} else {
+		for n := int64(1); n <= 9; n++ {
+			var node []byte
+			node = appendVarint(node, 1, zig(n))
+			node = appendVarint(node, 8, zig(coord(nodes[n].Latitude, latOffset)))
+			node = appendVarint(node, 9, zig(coord(nodes[n].Longitude, lngOffset)))
+			group = appendBytes(group, 1, node)
+		}
+	}
+
+	table := [][]byte{{}}
+	index := map[string]uint64{}
+	str := func(s string) uint64 {
+		if i, ok := index[s]; ok {
+			return i
+		}
+		index[s] = uint64(len(table))
+		table = append(table, []byte(s))
+		return index[s]
+	}
+
+	for i, w := range ways {
+		var way, keys, values, refs []byte
+		way = appendVarint(way, 1, uint64(10+i))
+		for k, v := range w.tags {
+			keys = uvarint(keys, str(k))
+			values = uvarint(values, str(v))
+		}
+		var prev int64
+		for _, ref := range w.refs {
+			refs = uvarint(refs, zig(ref-prev))
+			prev = ref
+		}
+		way = appendBytes(way, 2, keys)
+		way = appendBytes(way, 3, values)
+		way = appendBytes(way, 8, refs)
+		group = appendBytes(group, 3, way)
+	}
+
+	var strings []byte
+	for _, s := range table {
+		strings = appendBytes(strings, 1, s)
+	}
+
+	var block []byte
+	block = appendBytes(block, 1, strings)
+	block = appendBytes(block, 2, group)
+	block = appendVarint(block, 17, granularity)
+	block = appendVarint(block, 19, uint64(latOffset))
+	block = appendVarint(block, 20, uint64(lngOffset))
+	blob("OSMData", block)
+
+	return file.Bytes()
+}
+
+func appendVarint(b []byte, num int, v uint64) []byte {
+	return uvarint(uvarint(b, uint64(num)<<3), v)
+}
+
+func appendBytes(b []byte, num int, v []byte) []byte {
+	b = uvarint(b, uint64(num)<<3|2)
+	return append(uvarint(b, uint64(len(v))), v...)
+}
+
+func uvarint(b []byte, v uint64) []byte {
+
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func zig(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func sign(f float32) float64 {
+
+	if f < 0 {
+		return -1
+	}
+
+	return 1
+}
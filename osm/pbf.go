@@ -0,0 +1,401 @@
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/slamethendry/routific"
+)
+
+// Largest sizes of the parts of a PBF file, as set by its specification.
+const (
+	maxHeaderSize = 64 << 10
+	maxBlobSize   = 32 << 20
+)
+
+// ReadPBF reads the road network of an extract in the OSM PBF format, the
+// format of the Geofabrik downloads. Blocks must be uncompressed or zlib
+// compressed. The error matches routific.ErrInvalidInput if the extract is
+// malformed or has no drivable roads.
+// See [PBF Format]: https://wiki.openstreetmap.org/wiki/PBF_Format
+func ReadPBF(r io.Reader, opts ...Option) (*Graph, error) {
+
+	b := newBuilder(opts)
+
+	for {
+		if err := b.readBlob(r); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: osm pbf: %v", routific.ErrInvalidInput, err)
+		}
+	}
+
+	return b.graph()
+}
+
+// readBlob reads the next blob of a PBF file, returning io.EOF at the end of
+// the file.
+func (b *builder) readBlob(r io.Reader) error {
+
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxHeaderSize {
+		return fmt.Errorf("blob header of %d bytes", n)
+	}
+
+	header := make([]byte, n)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return unexpected(err)
+	}
+
+	var kind string
+	var dataSize uint64
+	err := readFields(header, func(f field) error {
+		switch f.num {
+		case 1:
+			kind = string(f.b)
+		case 3:
+			dataSize = f.u
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if dataSize > maxBlobSize {
+		return fmt.Errorf("blob of %d bytes", dataSize)
+	}
+
+	blob := make([]byte, dataSize)
+	if _, err := io.ReadFull(r, blob); err != nil {
+		return unexpected(err)
+	}
+	data, err := blobData(blob)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "OSMHeader":
+		return checkHeader(data)
+	case "OSMData":
+		return b.block(data)
+	}
+
+	return nil // unknown blobs are skipped
+}
+
+// unexpected turns the end of the file in the middle of a blob into an
+// error.
+func unexpected(err error) error {
+
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// blobData returns the uncompressed data of a blob.
+func blobData(blob []byte) ([]byte, error) {
+
+	var raw, compressed []byte
+	var rawSize uint64
+	err := readFields(blob, func(f field) error {
+		switch f.num {
+		case 1:
+			raw = f.b
+		case 2:
+			rawSize = f.u
+		case 3:
+			compressed = f.b
+		case 4, 5, 6, 7:
+			return errors.New("unsupported compression")
+		}
+		return nil
+	})
+	if err != nil || raw != nil {
+		return raw, err
+	}
+	if rawSize > maxBlobSize {
+		return nil, fmt.Errorf("blob of %d bytes", rawSize)
+	}
+
+	z, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, rawSize)
+	if _, err := io.ReadFull(z, data); err != nil {
+		return nil, unexpected(err)
+	}
+
+	return data, nil
+}
+
+// checkHeader checks that the file needs no more than nodes, dense nodes and
+// ways.
+func checkHeader(data []byte) error {
+
+	return readFields(data, func(f field) error {
+		if f.num != 4 {
+			return nil
+		}
+		switch feature := string(f.b); feature {
+		case "OsmSchema-V0.6", "DenseNodes":
+			return nil
+		default:
+			return fmt.Errorf("unsupported feature %s", feature)
+		}
+	})
+}
+
+// block reads the nodes and ways of a primitive block.
+func (b *builder) block(data []byte) error {
+
+	var table [][]byte
+	var groups [][]byte
+	granularity := int64(100)
+	var latOffset, lngOffset int64
+
+	err := readFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			return readFields(f.b, func(s field) error {
+				if s.num == 1 {
+					table = append(table, s.b)
+				}
+				return nil
+			})
+		case 2:
+			groups = append(groups, f.b)
+		case 17:
+			granularity = int64(f.u)
+		case 19:
+			latOffset = int64(f.u)
+		case 20:
+			lngOffset = int64(f.u)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// node adds a node, converting its stored coordinates into degrees
+	node := func(id, lat, lng int64) {
+		b.node(id,
+			1e-9*float64(latOffset+granularity*lat),
+			1e-9*float64(lngOffset+granularity*lng))
+	}
+
+	for _, group := range groups {
+		err := readFields(group, func(f field) error {
+			switch f.num {
+			case 1:
+				return pbfNode(f.b, node)
+			case 2:
+				return denseNodes(f.b, node)
+			case 3:
+				return b.pbfWay(f.b, table)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func pbfNode(data []byte, node func(id, lat, lng int64)) error {
+
+	var id, lat, lng int64
+	err := readFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			id = zigzag(f.u)
+		case 8:
+			lat = zigzag(f.u)
+		case 9:
+			lng = zigzag(f.u)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	node(id, lat, lng)
+	return nil
+}
+
+func denseNodes(data []byte, node func(id, lat, lng int64)) error {
+
+	var ids, lats, lngs []uint64
+	err := readFields(data, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			ids, err = f.varints(ids)
+		case 8:
+			lats, err = f.varints(lats)
+		case 9:
+			lngs, err = f.varints(lngs)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(lats) != len(ids) || len(lngs) != len(ids) {
+		return errors.New("dense nodes of different lengths")
+	}
+
+	// Values are deltas of the previous ones
+	var id, lat, lng int64
+	for i := range ids {
+		id += zigzag(ids[i])
+		lat += zigzag(lats[i])
+		lng += zigzag(lngs[i])
+		node(id, lat, lng)
+	}
+
+	return nil
+}
+
+func (b *builder) pbfWay(data []byte, table [][]byte) error {
+
+	var keys, values, refs []uint64
+	err := readFields(data, func(f field) error {
+		var err error
+		switch f.num {
+		case 2:
+			keys, err = f.varints(keys)
+		case 3:
+			values, err = f.varints(values)
+		case 8:
+			refs, err = f.varints(refs)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(keys) != len(values) {
+		return errors.New("way keys and values of different lengths")
+	}
+
+	tags := make(map[string]string, len(keys))
+	for i := range keys {
+		if keys[i] >= uint64(len(table)) || values[i] >= uint64(len(table)) {
+			return fmt.Errorf("string %d not in table", keys[i])
+		}
+		tags[string(table[keys[i]])] = string(table[values[i]])
+	}
+
+	nodes := make([]int64, len(refs))
+	var ref int64
+	for i := range refs {
+		ref += zigzag(refs[i]) // deltas of the previous ones
+		nodes[i] = ref
+	}
+
+	b.way(nodes, tags)
+	return nil
+}
+
+// field is a field of a protocol buffers message.
+type field struct {
+	num  int
+	wire int
+	u    uint64 // varint and fixed values
+	b    []byte // length-delimited values
+}
+
+// Wire types of protocol buffers.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// readFields calls fn for each field of a protocol buffers message.
+func readFields(msg []byte, fn func(f field) error) error {
+
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errors.New("malformed field key")
+		}
+		msg = msg[n:]
+
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.u, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return errors.New("malformed varint")
+			}
+			msg = msg[n:]
+		case wireFixed64:
+			if len(msg) < 8 {
+				return io.ErrUnexpectedEOF
+			}
+			f.u, msg = binary.LittleEndian.Uint64(msg), msg[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || size > uint64(len(msg)-n) {
+				return errors.New("malformed length")
+			}
+			f.b, msg = msg[n:n+int(size)], msg[n+int(size):]
+		case wireFixed32:
+			if len(msg) < 4 {
+				return io.ErrUnexpectedEOF
+			}
+			f.u, msg = uint64(binary.LittleEndian.Uint32(msg)), msg[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", f.wire)
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// varints appends the values of a repeated varint field to values, packed
+// or not.
+func (f field) varints(values []uint64) ([]uint64, error) {
+
+	if f.wire == wireVarint {
+		return append(values, f.u), nil
+	}
+
+	for b := f.b; len(b) > 0; {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("malformed packed varint")
+		}
+		values = append(values, v)
+		b = b[n:]
+	}
+
+	return values, nil
+}
+
+// zigzag decodes a signed varint.
+func zigzag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
@@ -0,0 +1,166 @@
+package osm
+
+import (
+	"container/heap"
+	"math"
+)
+
+// cellSize is the size in degrees of the cells of the snapping grid.
+const cellSize = 0.01
+
+// cell is a square of the snapping grid.
+type cell [2]int32
+
+func cellOf(lat, lng float64) cell {
+	return cell{int32(math.Floor(lat / cellSize)), int32(math.Floor(lng / cellSize))}
+}
+
+// nearest returns the nearest node of the grid to (lat, lng) and its
+// distance in km, or -1 if none is within the maximum snapping distance.
+func (g *Graph) nearest(lat, lng float64) (int32, float64) {
+
+	best, bestKm := int32(-1), math.Inf(1)
+	c := cellOf(lat, lng)
+
+	// Cells r rings away are at least r-1 cells away in longitude, which is
+	// the shorter side. Near the poles it shrinks to nothing, so it is
+	// floored to keep the number of rings bounded.
+	side := cellSize * degreeKm * math.Cos(lat*math.Pi/180)
+	side = math.Max(side, cellSize*degreeKm*0.01)
+
+	for r := int32(0); float64(r-1)*side <= math.Min(bestKm, g.maxSnap); r++ {
+		for dlat := -r; dlat <= r; dlat++ {
+			for dlng := -r; dlng <= r; dlng++ {
+				if dlat != -r && dlat != r && dlng != -r && dlng != r {
+					continue // inner rings are done
+				}
+				for _, n := range g.grid[cell{c[0] + dlat, c[1] + dlng}] {
+					if km := distance(lat, lng, g.lat[n], g.lng[n]); km < bestKm {
+						best, bestKm = n, km
+					}
+				}
+			}
+		}
+	}
+
+	if bestKm > g.maxSnap {
+		return -1, 0
+	}
+
+	return best, bestKm
+}
+
+// degreeKm is the length in km of a degree of latitude.
+const degreeKm = 111.195
+
+// distance returns the great-circle distance in km between two points.
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+
+	const earthRadius = 6371.0 // km
+
+	lat1 *= math.Pi / 180
+	lat2 *= math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (lng2 - lng1) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// search is a reusable shortest-path search from one node. Nodes not
+// reached have infinite minutes.
+type search struct {
+	g       *Graph
+	minutes []float64
+	km      []float64
+	settled []bool
+	touched []int32
+	queue   queue
+}
+
+func (g *Graph) newSearch() *search {
+
+	s := &search{
+		g:       g,
+		minutes: make([]float64, len(g.lat)),
+		km:      make([]float64, len(g.lat)),
+		settled: make([]bool, len(g.lat)),
+	}
+	for i := range s.minutes {
+		s.minutes[i] = math.Inf(1)
+	}
+
+	return s
+}
+
+// reached reports whether the last run reached n.
+func (s *search) reached(n int32) bool {
+	return !math.IsInf(s.minutes[n], 1)
+}
+
+// run finds the fastest routes from source, until every target is settled.
+func (s *search) run(source int32, targets []int32) {
+
+	for _, n := range s.touched {
+		s.minutes[n], s.km[n], s.settled[n] = math.Inf(1), 0, false
+	}
+	s.touched = s.touched[:0]
+	s.queue = s.queue[:0]
+
+	left := map[int32]bool{}
+	for _, n := range targets {
+		left[n] = true
+	}
+
+	s.minutes[source] = 0
+	s.touched = append(s.touched, source)
+	heap.Push(&s.queue, item{source, 0})
+
+	g := s.g
+	for len(s.queue) > 0 && len(left) > 0 {
+		it := heap.Pop(&s.queue).(item)
+		n := it.node
+		if s.settled[n] {
+			continue
+		}
+		s.settled[n] = true
+		delete(left, n)
+
+		for e := g.first[n]; e < g.first[n+1]; e++ {
+			to := g.to[e]
+			if t := s.minutes[n] + g.minutes[e]; t < s.minutes[to] {
+				if math.IsInf(s.minutes[to], 1) {
+					s.touched = append(s.touched, to)
+				}
+				s.minutes[to] = t
+				s.km[to] = s.km[n] + g.km[e]
+				heap.Push(&s.queue, item{to, t})
+			}
+		}
+	}
+}
+
+// item is a node in the search queue.
+type item struct {
+	node    int32
+	minutes float64
+}
+
+// queue is a min-heap of items by minutes.
+type queue []item
+
+func (q queue) Len() int            { return len(q) }
+func (q queue) Less(i, j int) bool  { return q[i].minutes < q[j].minutes }
+func (q queue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(item)) }
+
+func (q *queue) Pop() interface{} {
+
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return it
+}
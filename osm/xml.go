@@ -0,0 +1,83 @@
+package osm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/slamethendry/routific"
+)
+
+// xmlNode is a node element of an OSM XML file.
+type xmlNode struct {
+	ID  int64   `xml:"id,attr"`
+	Lat float64 `xml:"lat,attr"`
+	Lon float64 `xml:"lon,attr"`
+}
+
+// xmlWay is a way element of an OSM XML file.
+type xmlWay struct {
+	Nodes []struct {
+		Ref int64 `xml:"ref,attr"`
+	} `xml:"nd"`
+	Tags []struct {
+		Key   string `xml:"k,attr"`
+		Value string `xml:"v,attr"`
+	} `xml:"tag"`
+}
+
+// ReadXML reads the road network of an extract in the OSM XML format, as
+// exported from openstreetmap.org. The error matches routific.ErrInvalidInput
+// if the extract is malformed or has no drivable roads.
+// See [OSM XML]: https://wiki.openstreetmap.org/wiki/OSM_XML
+func ReadXML(r io.Reader, opts ...Option) (*Graph, error) {
+
+	b := newBuilder(opts)
+	dec := xml.NewDecoder(r)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: osm xml: %v", routific.ErrInvalidInput, err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "node":
+			var n xmlNode
+			if err := dec.DecodeElement(&n, &start); err != nil {
+				return nil, fmt.Errorf("%w: osm xml: %v", routific.ErrInvalidInput, err)
+			}
+			b.node(n.ID, n.Lat, n.Lon)
+
+		case "way":
+			var w xmlWay
+			if err := dec.DecodeElement(&w, &start); err != nil {
+				return nil, fmt.Errorf("%w: osm xml: %v", routific.ErrInvalidInput, err)
+			}
+			refs := make([]int64, len(w.Nodes))
+			for i, nd := range w.Nodes {
+				refs[i] = nd.Ref
+			}
+			tags := make(map[string]string, len(w.Tags))
+			for _, t := range w.Tags {
+				tags[t.Key] = t.Value
+			}
+			b.way(refs, tags)
+
+		case "relation":
+			if err := dec.Skip(); err != nil {
+				return nil, fmt.Errorf("%w: osm xml: %v", routific.ErrInvalidInput, err)
+			}
+		}
+	}
+
+	return b.graph()
+}
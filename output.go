@@ -0,0 +1,207 @@
+package routific
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Polylines are the encoded polylines of a vehicle's route, as returned when
+// Options.Polylines is set: either one for the whole route or one per leg.
+// See the polyline package to decode them.
+type Polylines []string
+
+// UnmarshalJSON reads a single polyline, or arrays of them, which it
+// flattens.
+func (p *Polylines) UnmarshalJSON(data []byte) error {
+
+	var lines Polylines
+	if err := flattenPolylines(data, &lines); err != nil {
+		return err
+	}
+
+	*p = lines
+	return nil
+}
+
+func flattenPolylines(data []byte, lines *Polylines) error {
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*lines = append(*lines, s)
+		return nil
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("%w: polylines %s, want strings", ErrInvalidInput, data)
+	}
+	for _, part := range parts {
+		if err := flattenPolylines(part, lines); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalJSON reads a Stop, keeping the fields it does not model in Extra.
+func (s *Stop) UnmarshalJSON(data []byte) error {
+
+	type stop Stop // without methods, to avoid recursion
+	var v stop
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	extra, err := unknownFields(data, v)
+	if err != nil {
+		return err
+	}
+
+	// A finish at "00:00" decodes to zero, as does none at all
+	var finish struct {
+		FinishTime *ClockTime `json:"finish_time"`
+	}
+	if err := json.Unmarshal(data, &finish); err != nil {
+		return err
+	}
+
+	*s = Stop(v)
+	s.FinishAtMidnight = finish.FinishTime != nil && *finish.FinishTime == 0
+	s.Extra = extra
+	return nil
+}
+
+// MarshalJSON writes a Stop, including the fields in Extra.
+func (s Stop) MarshalJSON() ([]byte, error) {
+
+	type stop Stop
+	if s.FinishTime == 0 && s.FinishAtMidnight {
+		return mergeJSON(s.Extra, stop(s), struct {
+			FinishTime ClockTime `json:"finish_time"`
+		}{})
+	}
+
+	return mergeJSON(s.Extra, stop(s))
+}
+
+// HasFinish reports whether the stop has a finish time: a non-zero
+// FinishTime, or midnight if FinishAtMidnight is set. Depots have none.
+func (s Stop) HasFinish() bool {
+	return s.FinishTime != 0 || s.FinishAtMidnight
+}
+
+// UnmarshalJSON reads a Schedule, keeping the fields it does not model in
+// Extra.
+func (s *Schedule) UnmarshalJSON(data []byte) error {
+
+	type schedule Schedule
+	var v schedule
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	extra, err := unknownFields(data, v)
+	if err != nil {
+		return err
+	}
+
+	*s = Schedule(v)
+	s.Extra = extra
+	return nil
+}
+
+// MarshalJSON writes a Schedule, including the fields in Extra.
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	type schedule Schedule
+	return mergeJSON(s.Extra, schedule(s))
+}
+
+// unknownFields returns the fields of the JSON object data that the struct v
+// does not have, or nil if there are none.
+func unknownFields(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	dropFields(fields, v)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return fields, nil
+}
+
+// dropFields deletes the fields of the struct v from fields.
+func dropFields(fields map[string]json.RawMessage, v interface{}) {
+	for _, name := range jsonNames(reflect.TypeOf(v)) {
+		delete(fields, name)
+	}
+}
+
+var jsonNamesCache sync.Map // reflect.Type -> []string
+
+// jsonNames returns the JSON field names of the struct type t.
+func jsonNames(t reflect.Type) []string {
+
+	if names, ok := jsonNamesCache.Load(t); ok {
+		return names.([]string)
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case name == "-" || !f.IsExported():
+			continue
+		case f.Anonymous && name == "":
+			names = append(names, jsonNames(f.Type)...)
+			continue
+		case name == "":
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+
+	jsonNamesCache.Store(t, names)
+	return names
+}
+
+// mergeJSON marshals the values, which must marshal into JSON objects, into
+// one object together with the extra fields. Fields of later values win, and
+// extra fields never replace modelled ones.
+func mergeJSON(extra map[string]json.RawMessage, values ...interface{}) ([]byte, error) {
+
+	if len(extra) == 0 && len(values) == 1 {
+		return json.Marshal(values[0])
+	}
+
+	fields := map[string]json.RawMessage{}
+	for _, v := range values {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var part map[string]json.RawMessage
+		if err := json.Unmarshal(b, &part); err != nil {
+			return nil, err
+		}
+		for k, raw := range part {
+			fields[k] = raw
+		}
+	}
+
+	for k, raw := range extra {
+		if _, ok := fields[k]; !ok {
+			fields[k] = raw
+		}
+	}
+
+	return json.Marshal(fields)
+}
@@ -0,0 +1,119 @@
+package routific_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// output_test checks that every documented output field is read, and that
+// fields not modelled are kept.
+
+const fullOutputJSON = `{
+	"status": "success",
+	"total_travel_time": 31.5,
+	"total_idle_time": 4,
+	"total_distance": 22.4,
+	"total_working_time": 95.5,
+	"num_unserved": 1,
+	"unserved": {"order_4": "Cannot be visited within the time windows"},
+	"vehicle_overtime": {"vehicle_1": 5},
+	"total_overtime": 5,
+	"polylines": {
+		"vehicle_1": ["_p~iF~ps|U_ulLnnqC", "_mqNvxq` + "`" + `@"],
+		"vehicle_2": "_p~iF~ps|U"
+	},
+	"solution": {
+		"vehicle_1": [
+			{"location_id": "depot", "location_name": "800 Kingsway",
+			 "arrival_time": "08:00"},
+			{"location_id": "order_1", "location_name": "6800 Cambie",
+			 "arrival_time": "08:13", "finish_time": "08:23",
+			 "distance": 7.1, "idle_time": 4, "eta_confidence": "high"}
+		]
+	},
+	"driver": {"name": "Sam", "phone": "555-0100"},
+	"engine_version": "2.3"
+}`
+
+func TestParseFullOutput(t *testing.T) {
+
+	var s r.Schedule
+	err := json.Unmarshal([]byte(fullOutputJSON), &s)
+	assert.Nil(t, err)
+
+	assert.Equal(t, float32(22.4), s.TotalDistance)
+	assert.Equal(t, float32(95.5), s.WorkingTime)
+	assert.Equal(t, r.VehicleOvertime{"vehicle_1": 5}, s.Overtime)
+	assert.Equal(t, "Cannot be visited within the time windows", s.Unserved["order_4"])
+	assert.Equal(t, map[string]r.Polylines{
+		"vehicle_1": {"_p~iF~ps|U_ulLnnqC", "_mqNvxq`@"},
+		"vehicle_2": {"_p~iF~ps|U"},
+	}, s.Polylines)
+
+	stop := s.Solution["vehicle_1"][1]
+	assert.Equal(t, float32(7.1), stop.Distance)
+	assert.Equal(t, float32(4), stop.IdleTime)
+	assert.Equal(t, map[string]json.RawMessage{
+		"eta_confidence": json.RawMessage(`"high"`),
+	}, stop.Extra)
+	assert.Nil(t, s.Solution["vehicle_1"][0].Extra)
+
+	assert.Equal(t, []string{"driver", "engine_version"}, keys(s.Extra))
+	assert.JSONEq(t, `{"name": "Sam", "phone": "555-0100"}`, string(s.Extra["driver"]))
+}
+
+func TestOutputRoundTrip(t *testing.T) {
+
+	var s r.Schedule
+	assert.Nil(t, json.Unmarshal([]byte(fullOutputJSON), &s))
+
+	b, err := json.Marshal(s)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"engine_version":"2.3"`)
+	assert.Contains(t, string(b), `"eta_confidence":"high"`)
+
+	var again r.Schedule
+	assert.Nil(t, json.Unmarshal(b, &again))
+	assert.Equal(t, s.Solution, again.Solution)
+	assert.Equal(t, keys(s.Extra), keys(again.Extra))
+	b2, err := json.Marshal(again)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(b), string(b2))
+
+	// A stop arriving at midnight keeps its arrival
+	b, err = json.Marshal(r.Stop{ID: "depot", ArrivalTime: r.Clock(0, 0)})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"location_id": "depot", "arrival_time": "00:00"}`, string(b))
+
+	// Modelled fields win over extra ones of the same name
+	s.Extra = map[string]json.RawMessage{"status": json.RawMessage(`"stale"`)}
+	b, err = json.Marshal(s)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"status":"success"`)
+}
+
+func TestTimedScheduleJSON(t *testing.T) {
+
+	var s r.Schedule
+	assert.Nil(t, json.Unmarshal([]byte(fullOutputJSON), &s))
+
+	loc := time.FixedZone("PDT", -7*60*60)
+	timed := s.Materialize(time.Date(2022, 6, 1, 0, 0, 0, 0, loc), loc)
+
+	b, err := json.Marshal(timed)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"arrival":"2022-06-01T08:13:00-07:00"`)
+	assert.Contains(t, string(b), `"location_id":"order_1"`)
+	assert.Contains(t, string(b), `"engine_version":"2.3"`)
+
+	var again r.TimedSchedule
+	assert.Nil(t, json.Unmarshal(b, &again))
+	assert.Equal(t, timed.Routes["vehicle_1"][1].Stop, again.Routes["vehicle_1"][1].Stop)
+	assert.True(t, timed.Routes["vehicle_1"][1].Arrival.Equal(
+		again.Routes["vehicle_1"][1].Arrival))
+	assert.Equal(t, keys(s.Extra), keys(again.Extra))
+}
@@ -0,0 +1,193 @@
+package routific
+
+import "sort"
+
+// Plan is a VRPlan or a PDPlan, for functions that work with both.
+type Plan interface {
+	// Vehicles returns the fleet of the plan by vehicle ID.
+	Vehicles() map[string]Vehicle
+
+	// visitLocation returns the location of the visit at stop s.
+	visitLocation(s Stop) (Location, bool)
+
+	// orderLocations returns the locations of the visit with the given key:
+	// one for a VRP visit, the pickup and the dropoff for a PDP order.
+	orderLocations(key string) []Location
+
+	// visitNotes returns the notes for the driver of the visit at stop s.
+	visitNotes(s Stop) string
+
+	// visitTerms returns the conditions of serving the visit at stop s.
+	visitTerms(s Stop) (terms, bool)
+}
+
+// terms are the conditions of serving a visit, a pickup or a dropoff.
+type terms struct {
+	windows  []TimeWindow // sorted by start; any time if empty
+	duration float32      // minutes
+	types    []string     // vehicle types allowed; any if empty
+	preload  Load         // loaded at the depot for this stop
+	change   Load         // loaded at this stop, negative if unloaded
+}
+
+// windows returns the time windows of start and end, or the ones of list if
+// any, sorted by start.
+func windows(start, end ClockTime, list []TimeWindow) []TimeWindow {
+
+	if len(list) == 0 {
+		if start == 0 && end == 0 {
+			return nil
+		}
+		list = []TimeWindow{{Start: start, End: end}}
+	}
+
+	sorted := append([]TimeWindow(nil), list...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	return sorted
+}
+
+var (
+	_ Plan = VRPlan{}
+	_ Plan = PDPlan{}
+)
+
+// Vehicles implements Plan.
+func (p VRPlan) Vehicles() map[string]Vehicle { return p.Fleet }
+
+func (p VRPlan) visitLocation(s Stop) (Location, bool) {
+	v, ok := p.Visits[s.ID]
+	return v.Location, ok
+}
+
+func (p VRPlan) orderLocations(key string) []Location {
+
+	if v, ok := p.Visits[key]; ok {
+		return []Location{v.Location}
+	}
+
+	return nil
+}
+
+func (p VRPlan) visitNotes(s Stop) string {
+	return p.Visits[s.ID].Notes
+}
+
+// visitTerms loads every visit at the depot, to be unloaded at the visit.
+func (p VRPlan) visitTerms(s Stop) (terms, bool) {
+
+	v, ok := p.Visits[s.ID]
+	if !ok {
+		return terms{}, false
+	}
+
+	t := terms{
+		windows:  windows(v.Start, v.End, v.TimeWindows),
+		duration: v.Duration,
+		preload:  v.Load.OrUnit(),
+		change:   Load{}.Sub(v.Load.OrUnit()),
+	}
+	if v.Type != "" {
+		t.types = []string{v.Type}
+	}
+
+	return t, true
+}
+
+// Vehicles implements Plan.
+func (p PDPlan) Vehicles() map[string]Vehicle { return p.Fleet }
+
+// visitLocation tells pickups from dropoffs by the type of the stop.
+func (p PDPlan) visitLocation(s Stop) (Location, bool) {
+
+	order, ok := p.Visits[s.ID]
+	if !ok {
+		return Location{}, false
+	}
+
+	switch s.Type {
+	case "pickup":
+		return order.PickUp.Location, true
+	case "dropoff":
+		return order.DropOff.Location, true
+	}
+
+	return Location{}, false
+}
+
+func (p PDPlan) orderLocations(key string) []Location {
+
+	if order, ok := p.Visits[key]; ok {
+		return []Location{order.PickUp.Location, order.DropOff.Location}
+	}
+
+	return nil
+}
+
+// visitNotes returns nothing, as orders have no notes.
+func (p PDPlan) visitNotes(s Stop) string { return "" }
+
+// visitTerms loads an order at its pickup and unloads it at its dropoff.
+func (p PDPlan) visitTerms(s Stop) (terms, bool) {
+
+	order, ok := p.Visits[s.ID]
+	if !ok {
+		return terms{}, false
+	}
+
+	var d Destination
+	var change Load
+	switch s.Type {
+	case "pickup":
+		d, change = order.PickUp, order.Load.OrUnit()
+	case "dropoff":
+		d, change = order.DropOff, Load{}.Sub(order.Load.OrUnit())
+	default:
+		return terms{}, false
+	}
+
+	return terms{
+		windows:  windows(d.Start, d.End, nil),
+		duration: d.Duration,
+		types:    order.Type,
+		change:   change,
+	}, true
+}
+
+// stopLocation returns the location of stops[i] in the route of the vehicle
+// with the given key: a visit, a depot, or a break with a location. Depots
+// are recognised by their ID, or else as the first and last stops.
+func stopLocation(p Plan, vehicleID string, stops Stops, i int) (Location, bool) {
+
+	s := stops[i]
+	v := p.Vehicles()[vehicleID]
+
+	if s.Break {
+		for _, b := range v.Breaks {
+			if b.ID == s.ID && b.Location != nil {
+				return *b.Location, true
+			}
+		}
+		return Location{}, false
+	}
+
+	if loc, ok := p.visitLocation(s); ok {
+		return loc, true
+	}
+
+	hasEnd := v.EndLocation != (Location{})
+	switch {
+	case s.ID != "" && s.ID == v.StartLocation.ID:
+		return v.StartLocation, true
+	case hasEnd && s.ID != "" && s.ID == v.EndLocation.ID:
+		return v.EndLocation, true
+	case i == 0 && v.StartLocation != (Location{}):
+		return v.StartLocation, true
+	case i == len(stops)-1 && hasEnd:
+		return v.EndLocation, true
+	}
+
+	return Location{}, false
+}
@@ -0,0 +1,221 @@
+package routific
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// PollConfig controls how WaitJob polls a long-running job.
+// See [Interval]: https://docs.routific.com/reference/vrp-long to choose the
+// interval according to the size of the plan.
+type PollConfig struct {
+	// Interval is the delay between the first two polls. Zero means polling
+	// again without pause.
+	Interval time.Duration
+
+	// Multiplier grows the delay after each poll, for adaptive polling of
+	// jobs of unknown length. Values up to 1 keep the delay fixed.
+	Multiplier float64
+
+	// MaxInterval caps the delay grown by Multiplier. Zero means no cap.
+	MaxInterval time.Duration
+
+	// Timeout is the total time to wait for the job, measured from the
+	// first poll. Zero means waiting until the context is done.
+	Timeout time.Duration
+
+	// MaxPolls is the maximum number of polls. Zero means no limit.
+	MaxPolls int
+
+	// MaxPollFailures is the number of consecutive polls that may fail
+	// with a transient error before giving up. Zero means none may fail.
+	MaxPollFailures int
+}
+
+// DefaultPollConfig polls every second at first, backing off to every 30
+// seconds, tolerates 3 failed polls in a row, and gives up after an hour.
+func DefaultPollConfig() PollConfig {
+	return PollConfig{
+		Interval:        time.Second,
+		Multiplier:      1.5,
+		MaxInterval:     30 * time.Second,
+		Timeout:         time.Hour,
+		MaxPollFailures: 3,
+	}
+}
+
+// WaitJob polls the job with the given ID according to cfg until it is
+// finished, and returns its schedule.
+// If the job failed, the error matches ErrJobFailed. If it did not finish
+// within cfg.Timeout or cfg.MaxPolls, the error matches ErrTimeout. If ctx is
+// done first, the error wraps ctx.Err().
+func (c *Client) WaitJob(
+	ctx context.Context,
+	id string,
+	cfg PollConfig,
+) (Schedule, error) {
+
+	p := poller{c: c, id: id, cfg: cfg, interval: cfg.Interval}
+	return p.run(ctx)
+}
+
+// pollState is a state of the poller.
+type pollState int
+
+const (
+	pollFetch pollState = iota // fetch the job status
+	pollWait                   // wait before fetching again
+	pollDone                   // stop with the outcome in result and err
+)
+
+// poller waits for a long-running job. It alternates between fetching the
+// job and waiting, until the job is done, the limits of cfg are reached, or
+// a poll fails for good.
+type poller struct {
+	c   *Client
+	id  string
+	cfg PollConfig
+
+	deadline time.Time     // zero if cfg.Timeout is zero
+	interval time.Duration // current delay between polls
+	polls    int           // polls made so far
+	failures int           // consecutive failed polls
+	lastErr  error         // error of the latest failed poll
+
+	result Schedule
+	err    error
+}
+
+func (p *poller) run(ctx context.Context) (Schedule, error) {
+
+	if p.cfg.Timeout > 0 {
+		p.deadline = time.Now().Add(p.cfg.Timeout)
+	}
+
+	state := pollFetch
+	for state != pollDone {
+		switch state {
+		case pollFetch:
+			state = p.fetch(ctx)
+		case pollWait:
+			state = p.wait(ctx)
+		}
+	}
+
+	return p.result, p.err
+}
+
+func (p *poller) fetch(ctx context.Context) pollState {
+
+	p.polls++
+	res, err := p.c.fetchJob(ctx, p.id)
+
+	if ctx.Err() != nil {
+		return p.cancel(ctx)
+	}
+
+	if err != nil {
+		p.failures++
+		p.lastErr = err
+		if !transientPollError(err) || p.failures > p.cfg.MaxPollFailures {
+			p.err = err
+			return pollDone
+		}
+		return p.next()
+	}
+
+	p.failures = 0
+	p.lastErr = nil
+
+	if res.Status == JobFinished || res.Status == JobError {
+		p.result, p.err = p.c.jobResult(res)
+		return pollDone
+	}
+
+	return p.next()
+}
+
+// next decides whether there is room for another poll.
+func (p *poller) next() pollState {
+
+	if p.cfg.MaxPolls > 0 && p.polls >= p.cfg.MaxPolls {
+		p.err = p.timeout(fmt.Sprintf("after %d polls", p.polls))
+		return pollDone
+	}
+
+	if !p.deadline.IsZero() && !time.Now().Before(p.deadline) {
+		p.err = p.timeout(fmt.Sprintf("after %s", p.cfg.Timeout))
+		return pollDone
+	}
+
+	return pollWait
+}
+
+func (p *poller) wait(ctx context.Context) pollState {
+
+	d := p.interval
+	if !p.deadline.IsZero() {
+		// Poll one last time at the deadline rather than overshooting it
+		if remaining := time.Until(p.deadline); d > remaining {
+			d = remaining
+		}
+	}
+
+	if p.cfg.Multiplier > 1 {
+		p.interval = time.Duration(float64(p.interval) * p.cfg.Multiplier)
+		if p.cfg.MaxInterval > 0 && p.interval > p.cfg.MaxInterval {
+			p.interval = p.cfg.MaxInterval
+		}
+	}
+
+	if d <= 0 {
+		return pollFetch
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return p.cancel(ctx)
+	case <-t.C:
+		return pollFetch
+	}
+}
+
+func (p *poller) cancel(ctx context.Context) pollState {
+	p.err = fmt.Errorf("waiting for job %s: %w", p.id, ctx.Err())
+	return pollDone
+}
+
+// timeout returns the error for a job that did not finish in time, naming
+// the latest poll failure, if any.
+func (p *poller) timeout(msg string) error {
+
+	if p.lastErr != nil {
+		msg = fmt.Sprintf("%s, last poll failed: %v", msg, p.lastErr)
+	}
+
+	return &APIError{
+		Message: msg,
+		URL:     p.c.url(jobURL(p.id)),
+		JobID:   p.id,
+		Err:     ErrTimeout,
+	}
+}
+
+// transientPollError reports whether a failed poll is worth repeating, as
+// opposed to e.g. an unknown job or a revoked token.
+func transientPollError(err error) bool {
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		return apiErr.StatusCode >= 500 ||
+			apiErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	return IsTransient(err)
+}
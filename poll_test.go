@@ -0,0 +1,151 @@
+package routific_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// poll_test checks how WaitJob copes with slow jobs and failing polls.
+// Test data is defined in setup_test.
+
+// jobServer answers the polls of job "abc" with the given status codes and
+// bodies in turn, repeating the last one, and counts the polls.
+func jobServer(statuses []int, bodies []string, polls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			i := *polls
+			if i >= len(bodies) {
+				i = len(bodies) - 1
+			}
+			*polls++
+			w.WriteHeader(statuses[i])
+			w.Write([]byte(bodies[i]))
+		}))
+}
+
+func TestWaitJobToleratesFailedPolls(t *testing.T) {
+
+	polls := 0
+	srv := jobServer(
+		[]int{200, 503, 502, 200},
+		[]string{
+			`{"id": "abc", "status": "processing"}`,
+			"", "",
+			`{"id": "abc", "status": "finished", "output": ` + vrpOutputJSON + `}`,
+		}, &polls)
+	defer srv.Close()
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL), r.WithRetryPolicy(r.NoRetry))
+
+	output, err := c.WaitJob(context.Background(), "abc", r.PollConfig{
+		Interval:        time.Millisecond,
+		MaxPollFailures: 2,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, vrpOutput, output)
+	assert.Equal(t, 4, polls)
+}
+
+func TestWaitJobTooManyFailedPolls(t *testing.T) {
+
+	polls := 0
+	srv := jobServer([]int{503}, []string{`{"error": "down"}`}, &polls)
+	defer srv.Close()
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL), r.WithRetryPolicy(r.NoRetry))
+
+	_, err := c.WaitJob(context.Background(), "abc", r.PollConfig{
+		Interval:        time.Millisecond,
+		MaxPollFailures: 2,
+	})
+
+	var apiErr *r.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 503, apiErr.StatusCode)
+	assert.Equal(t, "abc", apiErr.JobID)
+	assert.Equal(t, "down", apiErr.Message)
+	assert.Equal(t, 3, polls)
+}
+
+func TestWaitJobPermanentFailure(t *testing.T) {
+
+	polls := 0
+	srv := jobServer([]int{404}, []string{`{"error": "no such job"}`}, &polls)
+	defer srv.Close()
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL), r.WithRetryPolicy(r.NoRetry))
+
+	_, err := c.WaitJob(context.Background(), "abc", r.PollConfig{
+		MaxPollFailures: 5,
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, polls)
+}
+
+func TestWaitJobDeadline(t *testing.T) {
+
+	polls := 0
+	srv := jobServer(
+		[]int{200, 503},
+		[]string{`{"id": "abc", "status": "pending"}`, ""},
+		&polls)
+	defer srv.Close()
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL), r.WithRetryPolicy(r.NoRetry))
+
+	start := time.Now()
+	_, err := c.WaitJob(context.Background(), "abc", r.PollConfig{
+		Interval:        20 * time.Millisecond,
+		Timeout:         150 * time.Millisecond,
+		MaxPollFailures: 100,
+	})
+	elapsed := time.Since(start)
+
+	assert.True(t, errors.Is(err, r.ErrTimeout))
+	assert.Contains(t, err.Error(), "last poll failed")
+	assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+}
+
+func TestWaitJobAdaptiveInterval(t *testing.T) {
+
+	polls := 0
+	srv := jobServer([]int{200}, []string{`{"id": "abc", "status": "pending"}`},
+		&polls)
+	defer srv.Close()
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL))
+
+	// Waits 10, 20, 40, 40, 40... ms between polls
+	_, err := c.WaitJob(context.Background(), "abc", r.PollConfig{
+		Interval:    10 * time.Millisecond,
+		Multiplier:  2,
+		MaxInterval: 40 * time.Millisecond,
+		Timeout:     250 * time.Millisecond,
+	})
+
+	assert.True(t, errors.Is(err, r.ErrTimeout))
+	assert.GreaterOrEqual(t, polls, 5)
+	assert.LessOrEqual(t, polls, 9)
+}
+
+func TestWaitJobMaxPolls(t *testing.T) {
+
+	polls := 0
+	srv := jobServer([]int{200}, []string{`{"id": "abc", "status": "pending"}`},
+		&polls)
+	defer srv.Close()
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL))
+
+	_, err := c.WaitJob(context.Background(), "abc", r.PollConfig{MaxPolls: 4})
+	assert.True(t, errors.Is(err, r.ErrTimeout))
+	assert.Equal(t, 4, polls)
+}
@@ -0,0 +1,52 @@
+// Package polyline decodes and encodes routes in the encoded polyline format
+// that Routific returns when Options.Polylines is set, as used by Google
+// Maps. Points are stored with a fixed number of decimals, the precision,
+// which is 5 unless stated otherwise.
+// See [Format]: https://developers.google.com/maps/documentation/utilities/polylinealgorithm
+package polyline
+
+import (
+	"fmt"
+
+	"github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/internal/polyenc"
+)
+
+// DefaultPrecision is the number of decimals of Google-style polylines.
+const DefaultPrecision = 5
+
+// Decode decodes a polyline with the default precision.
+func Decode(s string) ([]routific.Location, error) {
+	return DecodePrecision(s, DefaultPrecision)
+}
+
+// DecodePrecision decodes a polyline with the given number of decimals,
+// e.g. 6 for OSRM and Valhalla. The error matches routific.ErrInvalidInput.
+func DecodePrecision(s string, precision int) ([]routific.Location, error) {
+
+	var path []routific.Location
+	err := polyenc.Decode(s, precision, func(lat, lng float64) {
+		path = append(path, routific.Location{
+			Latitude:  float32(lat),
+			Longitude: float32(lng),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", routific.ErrInvalidInput, err)
+	}
+
+	return path, nil
+}
+
+// Encode encodes a path with the default precision. Only the coordinates
+// of the locations are kept.
+func Encode(path []routific.Location) string {
+	return EncodePrecision(path, DefaultPrecision)
+}
+
+// EncodePrecision encodes a path with the given number of decimals.
+func EncodePrecision(path []routific.Location, precision int) string {
+	return polyenc.Encode(len(path), precision, func(i int) (float64, float64) {
+		return float64(path[i].Latitude), float64(path[i].Longitude)
+	})
+}
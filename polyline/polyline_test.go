@@ -0,0 +1,71 @@
+package polyline_test
+
+import (
+	"errors"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/polyline"
+	"github.com/stretchr/testify/assert"
+)
+
+// polyline_test checks decoding and encoding against the example of the
+// polyline format documentation.
+
+const example = "_p~iF~ps|U_ulLnnqC_mqNvxq`@"
+
+var examplePath = []r.Location{
+	{Latitude: 38.5, Longitude: -120.2},
+	{Latitude: 40.7, Longitude: -120.95},
+	{Latitude: 43.252, Longitude: -126.453},
+}
+
+func TestDecode(t *testing.T) {
+
+	path, err := polyline.Decode(example)
+	assert.Nil(t, err)
+	assert.Equal(t, examplePath, path)
+
+	path, err = polyline.Decode("")
+	assert.Nil(t, err)
+	assert.Empty(t, path)
+}
+
+func TestEncode(t *testing.T) {
+
+	assert.Equal(t, example, polyline.Encode(examplePath))
+	assert.Equal(t, "", polyline.Encode(nil))
+}
+
+func TestPrecision(t *testing.T) {
+
+	path := []r.Location{
+		{Latitude: 49.255363, Longitude: -123.087336},
+		{Latitude: 49.227107, Longitude: -123.116308},
+	}
+
+	s := polyline.EncodePrecision(path, 6)
+	got, err := polyline.DecodePrecision(s, 6)
+	assert.Nil(t, err)
+	for i := range path {
+		assert.InDelta(t, path[i].Latitude, got[i].Latitude, 1e-5)
+		assert.InDelta(t, path[i].Longitude, got[i].Longitude, 1e-5)
+	}
+
+	// Decoding with the wrong precision scales the coordinates
+	got, err = polyline.Decode(s)
+	assert.Nil(t, err)
+	assert.InDelta(t, 492.55363, got[0].Latitude, 1e-2)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+
+	for _, s := range []string{
+		"_p~iF",       // latitude without longitude
+		"_p~iF~ps|",   // truncated value
+		"_p~iF~ps|U ", // byte out of range
+	} {
+		_, err := polyline.Decode(s)
+		assert.True(t, errors.Is(err, r.ErrInvalidInput), s)
+	}
+}
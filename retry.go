@@ -0,0 +1,182 @@
+package routific
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides whether a failed request is sent again.
+// Implementations must be safe for concurrent use.
+type RetryPolicy interface {
+	// Retry is called after the attempt-th attempt (counting from 1) of a
+	// request failed with err. It reports how long to wait before the next
+	// attempt, and whether there should be one at all.
+	Retry(attempt int, err error) (time.Duration, bool)
+}
+
+// NoRetry is a RetryPolicy that never retries.
+var NoRetry RetryPolicy = noRetry{}
+
+type noRetry struct{}
+
+func (noRetry) Retry(int, error) (time.Duration, bool) { return 0, false }
+
+// ExponentialBackoff is a RetryPolicy that waits BaseDelay, then twice as
+// long after each further failure, up to MaxDelay. A Retry-After header sent
+// with the failed response takes precedence over the computed delay.
+type ExponentialBackoff struct {
+	MaxAttempts int           // total attempts, including the first one
+	BaseDelay   time.Duration // delay before the second attempt
+	MaxDelay    time.Duration // cap of the computed delay; 0 means no cap
+
+	// Jitter randomises each delay by up to this fraction of it, e.g. 0.2
+	// for +/- 20%, so that many clients do not retry in lockstep.
+	Jitter float64
+
+	// RetryableStatus lists the HTTP status codes worth retrying.
+	RetryableStatus []int
+
+	// RetryableError reports whether a failure other than an HTTP status is
+	// worth retrying. If nil, network errors and timeouts are retried.
+	RetryableError func(error) bool
+}
+
+// DefaultRetryPolicy returns the policy used by clients created without
+// WithRetryPolicy: up to 3 attempts on network errors and 429, 502, 503, or
+// 504, starting at 500ms apart.
+//
+// Whatever the policy, solve requests (POSTs) are only sent again if they
+// cannot have reached Routific, unless the client is created WithRetryPosts.
+func DefaultRetryPolicy() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		RetryableStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// Retry implements RetryPolicy.
+func (b *ExponentialBackoff) Retry(attempt int, err error) (time.Duration, bool) {
+
+	if attempt >= b.MaxAttempts || !b.retryable(err) {
+		return 0, false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, true
+	}
+
+	d := float64(b.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if b.MaxDelay > 0 && d > float64(b.MaxDelay) {
+		d = float64(b.MaxDelay)
+	}
+	if b.Jitter > 0 {
+		d += d * b.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(d), true
+}
+
+func (b *ExponentialBackoff) retryable(err error) bool {
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		for _, code := range b.RetryableStatus {
+			if code == apiErr.StatusCode {
+				return true
+			}
+		}
+		return false
+	}
+
+	if b.RetryableError != nil {
+		return b.RetryableError(err)
+	}
+
+	return IsTransient(err)
+}
+
+// IsTransient reports whether err is a network error or timeout that may not
+// recur if the request is sent again.
+func IsTransient(err error) bool {
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// WithRetryPosts lets the retry policy send solve requests again after any
+// failure it deems retryable. By default, a POST is only retried when it
+// failed to connect, or was refused with 429 or 503 and a Retry-After header,
+// since a POST that timed out or failed at a gateway may still have been
+// solved, and billed, or submitted as a job.
+func WithRetryPosts() Option {
+	return func(c *Client) {
+		c.retryPosts = true
+	}
+}
+
+// notSent reports whether a request that failed with err cannot have been
+// processed by Routific: it failed to connect, or was turned away with a
+// Retry-After header.
+func notSent(err error) bool {
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter > 0 &&
+			(apiErr.StatusCode == http.StatusTooManyRequests ||
+				apiErr.StatusCode == http.StatusServiceUnavailable)
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// WithRetryPolicy sets the policy for retrying failed requests, including
+// the polling of long-running jobs. Use NoRetry to disable retries.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an
+// HTTP date. It returns 0 if the header is absent or invalid.
+func retryAfter(header string, now time.Time) time.Duration {
+
+	if header == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(header); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+
+	if t, err := http.ParseTime(header); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,148 @@
+package routific_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/routifictest"
+	"github.com/stretchr/testify/assert"
+)
+
+// retry_test checks that transient failures are retried according to the
+// client's RetryPolicy.
+// Test data is defined in setup_test.
+
+// countingPolicy retries every failure up to max attempts without waiting.
+type countingPolicy struct {
+	max  int
+	errs []error
+}
+
+func (p *countingPolicy) Retry(attempt int, err error) (time.Duration, bool) {
+	p.errs = append(p.errs, err)
+	return 0, attempt < p.max
+}
+
+func TestRetryTransientStatus(t *testing.T) {
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			if calls < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(vrpOutputJSON))
+		}))
+	defer srv.Close()
+
+	policy := r.DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL), r.WithRetryPolicy(policy),
+		r.WithRetryPosts())
+
+	output, err := c.VRP(vrpInput)
+	assert.Nil(t, err)
+	assert.Equal(t, vrpOutput, output)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryAfter(t *testing.T) {
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			if calls == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(pdpOutputJSON))
+		}))
+	defer srv.Close()
+
+	policy := r.DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL), r.WithRetryPolicy(policy))
+
+	start := time.Now()
+	output, err := c.PDP(pdpInput)
+	assert.Nil(t, err)
+	assert.Equal(t, pdpOutput, output)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestRetryPolicyInterface(t *testing.T) {
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+	defer srv.Close()
+
+	policy := &countingPolicy{max: 4}
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL), r.WithRetryPolicy(policy),
+		r.WithRetryPosts())
+
+	_, err := c.VRP(vrpInput)
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+	assert.Equal(t, 4, calls)
+	assert.Len(t, policy.errs, 4)
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+	defer srv.Close()
+
+	c := r.NewClient("secret", r.WithBaseURL(srv.URL))
+
+	_, err := c.VRP(vrpInput)
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryPostNotResent(t *testing.T) {
+
+	// A solve that timed out may still have been processed
+	srv := routifictest.NewServer(routifictest.WithLatency(200 * time.Millisecond))
+	defer srv.Close()
+
+	policy := r.DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+
+	c := srv.Client(r.WithTimeout(20*time.Millisecond), r.WithRetryPolicy(policy))
+	_, err := c.VRP(vrpInput)
+	assert.NotNil(t, err)
+	assert.Len(t, srv.Requests(), 1)
+
+	// Nor one that failed at a gateway
+	srv.SetLatency(0)
+	srv.FailNext("/v1/vrp", http.StatusBadGateway, "")
+	c = srv.Client(r.WithRetryPolicy(policy))
+	_, err = c.VRP(vrpInput)
+	assert.NotNil(t, err)
+	assert.Len(t, srv.Requests(), 2)
+
+	// Unless asked to
+	srv.FailNext("/v1/vrp", http.StatusBadGateway, "")
+	c = srv.Client(r.WithRetryPolicy(policy), r.WithRetryPosts())
+	_, err = c.VRP(vrpInput)
+	assert.Nil(t, err)
+	assert.Len(t, srv.Requests(), 4)
+}
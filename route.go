@@ -0,0 +1,44 @@
+package routific
+
+// routeStop is a stop of a route with its location.
+type routeStop struct {
+	Stop
+	seq  int    // index in the route
+	kind string // "visit", "depot" or "break"
+	loc  Location
+}
+
+// routeStops returns the stops of the vehicle's route that have a location
+// in plan.
+func routeStops(plan Plan, vehicleID string, stops Stops) []routeStop {
+
+	var out []routeStop
+
+	for i, stop := range stops {
+		loc, ok := stopLocation(plan, vehicleID, stops, i)
+		if !ok {
+			continue
+		}
+
+		kind := "visit"
+		if stop.Break {
+			kind = "break"
+		} else if _, visit := plan.visitLocation(stop); !visit {
+			kind = "depot"
+		}
+
+		out = append(out, routeStop{Stop: stop, seq: i, kind: kind, loc: loc})
+	}
+
+	return out
+}
+
+// label returns the name of the stop, or else its ID.
+func (s routeStop) label() string {
+
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return s.ID
+}
@@ -0,0 +1,348 @@
+// Package routifictest provides a local stand-in for the Routific Engine API,
+// for testing code that uses package routific without network access or a
+// Routific token.
+//
+// The server answers /v1/vrp, /v1/pdp, /v1/vrp-long, /v1/pdp-long and
+// /jobs/{id} with canned schedules, steps long-running jobs through a
+// scripted sequence of states, and can be told to check the token, to delay
+// its answers, or to fail requests.
+package routifictest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slamethendry/routific"
+)
+
+// JobStep is the state reported by one poll of a long-running job.
+type JobStep struct {
+	Status   routific.JobState
+	Progress string
+	Message  string // output of the job if Status is routific.JobError
+}
+
+// DefaultJobScript moves a job from pending to processing to finished, one
+// step per poll.
+var DefaultJobScript = []JobStep{
+	{Status: routific.JobPending},
+	{Status: routific.JobProcessing, Progress: "Optimizing"},
+	{Status: routific.JobFinished},
+}
+
+// Request is a request received by the Server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Server is a fake Routific Engine API listening on a local address.
+// Its configuration may be changed while it is running.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	token    string
+	vrp      routific.Schedule
+	pdp      routific.Schedule
+	script   []JobStep
+	latency  time.Duration
+	failures map[string][]failure
+	jobs     map[string]*job
+	requests []Request
+	nextID   int
+}
+
+type failure struct {
+	status int
+	body   string
+}
+
+type job struct {
+	output routific.Schedule
+	script []JobStep
+	polls  int
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithToken makes the server reject requests not authenticated with token.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithVRPSchedule sets the schedule returned for VRP plans.
+func WithVRPSchedule(schedule routific.Schedule) Option {
+	return func(s *Server) {
+		s.vrp = schedule
+	}
+}
+
+// WithPDPSchedule sets the schedule returned for PDP plans.
+func WithPDPSchedule(schedule routific.Schedule) Option {
+	return func(s *Server) {
+		s.pdp = schedule
+	}
+}
+
+// WithJobScript sets the states that each new long-running job goes through,
+// one per poll. The last state repeats once reached.
+func WithJobScript(steps ...JobStep) Option {
+	return func(s *Server) {
+		s.script = steps
+	}
+}
+
+// WithLatency delays every response by d.
+func WithLatency(d time.Duration) Option {
+	return func(s *Server) {
+		s.latency = d
+	}
+}
+
+// NewServer starts a Server configured by opts. The caller should call Close
+// when finished, to shut it down.
+func NewServer(opts ...Option) *Server {
+
+	s := &Server{
+		vrp:      routific.Schedule{Status: "success"},
+		pdp:      routific.Schedule{Status: "success"},
+		script:   DefaultJobScript,
+		failures: map[string][]failure{},
+		jobs:     map[string]*job{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a routific.Client for the server, authenticated with the
+// server's token. Further options are applied after the base URL and token.
+func (s *Server) Client(opts ...routific.Option) *routific.Client {
+
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+
+	opts = append([]routific.Option{
+		routific.WithBaseURL(s.URL),
+		routific.WithHTTPClient(s.Server.Client()),
+	}, opts...)
+
+	return routific.NewClient(token, opts...)
+}
+
+// SetVRPSchedule changes the schedule returned for VRP plans submitted from
+// now on.
+func (s *Server) SetVRPSchedule(schedule routific.Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vrp = schedule
+}
+
+// SetPDPSchedule changes the schedule returned for PDP plans submitted from
+// now on.
+func (s *Server) SetPDPSchedule(schedule routific.Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pdp = schedule
+}
+
+// SetJobScript changes the states of jobs submitted from now on.
+func (s *Server) SetJobScript(steps ...JobStep) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script = steps
+}
+
+// SetLatency changes the delay of every response.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// FailNext makes the next request to path fail with the given HTTP status
+// and body. Path is either an endpoint such as "/v1/vrp", or "/jobs" for the
+// polls of any job. Calls queue up, one failure per request.
+func (s *Server) FailNext(path string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], failure{status, body})
+}
+
+// Requests returns the requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Polls returns the number of times the job with the given ID was polled.
+func (s *Server) Polls(id string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[id]; ok {
+		return j.polls
+	}
+	return 0
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+
+	body, _ := ioutil.ReadAll(req.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Header: req.Header.Clone(),
+		Body:   body,
+	})
+	latency := s.latency
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-time.After(latency):
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && req.Header.Get("Authorization") != "bearer "+s.token {
+		writeError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	key := req.URL.Path
+	if strings.HasPrefix(key, "/jobs/") {
+		key = "/jobs"
+	}
+	if queue := s.failures[key]; len(queue) > 0 {
+		s.failures[key] = queue[1:]
+		w.WriteHeader(queue[0].status)
+		w.Write([]byte(queue[0].body))
+		return
+	}
+
+	switch req.URL.Path {
+	case "/v1/vrp":
+		s.solve(w, req, body, &routific.VRPlan{}, s.vrp, false)
+	case "/v1/pdp":
+		s.solve(w, req, body, &routific.PDPlan{}, s.pdp, false)
+	case "/v1/vrp-long":
+		s.solve(w, req, body, &routific.VRPlan{}, s.vrp, true)
+	case "/v1/pdp-long":
+		s.solve(w, req, body, &routific.PDPlan{}, s.pdp, true)
+	default:
+		if strings.HasPrefix(req.URL.Path, "/jobs/") {
+			s.poll(w, req, strings.TrimPrefix(req.URL.Path, "/jobs/"))
+			return
+		}
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// solve answers a plan with the schedule, or with a job that will finish with
+// it if long is set. Must be called with s.mu held.
+func (s *Server) solve(
+	w http.ResponseWriter,
+	req *http.Request,
+	body []byte,
+	plan interface{},
+	schedule routific.Schedule,
+	long bool,
+) {
+
+	if req.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if err := json.Unmarshal(body, plan); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !long {
+		writeJSON(w, http.StatusOK, schedule)
+		return
+	}
+
+	s.nextID++
+	id := fmt.Sprintf("job-%d", s.nextID)
+	s.jobs[id] = &job{output: schedule, script: s.script}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"job_id": id})
+}
+
+// poll answers with the next scripted state of a job. Must be called with
+// s.mu held.
+func (s *Server) poll(w http.ResponseWriter, req *http.Request, id string) {
+
+	if req.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	j, ok := s.jobs[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	step := JobStep{Status: routific.JobFinished}
+	if len(j.script) > 0 {
+		i := j.polls
+		if i >= len(j.script) {
+			i = len(j.script) - 1
+		}
+		step = j.script[i]
+	}
+	j.polls++
+
+	res := map[string]interface{}{
+		"id":     id,
+		"status": step.Status,
+	}
+	if step.Progress != "" {
+		res["progress"] = step.Progress
+	}
+	switch step.Status {
+	case routific.JobFinished:
+		res["output"] = j.output
+	case routific.JobError:
+		res["output"] = step.Message
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,140 @@
+package routifictest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/routifictest"
+	"github.com/stretchr/testify/assert"
+)
+
+// server_test checks the fake server against package routific's client,
+// including the polling of long-running jobs.
+
+var plan = r.VRPlan{
+	Visits: map[string]r.Visit{
+		"order_1": {Location: r.Location{Name: "6800 Cambie"}},
+	},
+	Fleet: map[string]r.Vehicle{
+		"vehicle_1": {StartLocation: r.Location{ID: "depot"}},
+	},
+}
+
+var schedule = r.Schedule{
+	Status:     "success",
+	TravelTime: 12.5,
+	Solution: map[string]r.Stops{
+		"vehicle_1": {{ID: "depot"}, {ID: "order_1", Name: "6800 Cambie"}},
+	},
+}
+
+func TestServerVRP(t *testing.T) {
+
+	srv := routifictest.NewServer(
+		routifictest.WithToken("secret"),
+		routifictest.WithVRPSchedule(schedule),
+	)
+	defer srv.Close()
+
+	output, err := srv.Client().VRP(plan)
+	assert.Nil(t, err)
+	assert.Equal(t, schedule, output)
+
+	requests := srv.Requests()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "/v1/vrp", requests[0].Path)
+	assert.Equal(t, "bearer secret", requests[0].Header.Get("Authorization"))
+}
+
+func TestServerAuth(t *testing.T) {
+
+	srv := routifictest.NewServer(routifictest.WithToken("secret"))
+	defer srv.Close()
+
+	c := r.NewClient("wrong", r.WithBaseURL(srv.URL))
+
+	_, err := c.PDP(r.PDPlan{})
+	assert.True(t, errors.Is(err, r.ErrUnauthorized))
+}
+
+func TestServerLongJob(t *testing.T) {
+
+	srv := routifictest.NewServer(routifictest.WithVRPSchedule(schedule))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := srv.Client()
+
+	job, err := c.SubmitVRPJob(ctx, plan)
+	assert.Nil(t, err)
+
+	for _, want := range []r.JobState{r.JobPending, r.JobProcessing} {
+		status, err := c.JobStatus(ctx, job.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, want, status.Status)
+	}
+
+	output, err := c.JobResult(ctx, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, schedule, output)
+	assert.Equal(t, 3, srv.Polls(job.ID))
+
+	output, err = c.LongVRP(plan, 0, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, schedule, output)
+}
+
+func TestServerJobError(t *testing.T) {
+
+	srv := routifictest.NewServer(routifictest.WithJobScript(
+		routifictest.JobStep{Status: r.JobProcessing},
+		routifictest.JobStep{Status: r.JobError, Message: "No vehicles"},
+	))
+	defer srv.Close()
+
+	_, err := srv.Client().LongPDP(r.PDPlan{}, 0, 5)
+	assert.True(t, errors.Is(err, r.ErrJobFailed))
+	assert.Contains(t, err.Error(), "No vehicles")
+}
+
+func TestServerFailedPolls(t *testing.T) {
+
+	srv := routifictest.NewServer(routifictest.WithVRPSchedule(schedule))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := srv.Client(r.WithRetryPolicy(r.NoRetry))
+
+	job, err := c.SubmitVRPJob(ctx, plan)
+	assert.Nil(t, err)
+
+	srv.FailNext("/jobs", 503, `{"error": "busy"}`)
+	srv.FailNext("/jobs", 502, "")
+
+	output, err := c.WaitJob(ctx, job.ID, r.PollConfig{
+		Interval:        time.Millisecond,
+		MaxPollFailures: 2,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, schedule, output)
+	assert.Equal(t, 3, srv.Polls(job.ID))
+}
+
+func TestServerLatency(t *testing.T) {
+
+	srv := routifictest.NewServer(routifictest.WithLatency(time.Second))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := srv.Client().VRPContext(ctx, plan)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	srv.SetLatency(0)
+	_, err = srv.Client().VRP(plan)
+	assert.Nil(t, err)
+}
@@ -301,32 +301,32 @@ var pdpOutputJSON = `
 var pdpInput = r.PDPlan{
 	Visits: map[string]r.PickDropOrder{
 		"order_1": {
-			Load: 1,
+			Load: r.Units(1),
 			PickUp: r.Destination{
 				Location: arbutus,
-				Start:    "9:00",
-				End:      "12:00",
+				Start:    r.Clock(9, 0),
+				End:      r.Clock(12, 0),
 				Duration: 10,
 			},
 			DropOff: r.Destination{
 				Location: cambie,
-				Start:    "9:00",
-				End:      "12:00",
+				Start:    r.Clock(9, 0),
+				End:      r.Clock(12, 0),
 				Duration: 10,
 			},
 		},
 		"order_2": {
-			Load: 1,
+			Load: r.Units(1),
 			PickUp: r.Destination{
 				Location: arbutus,
-				Start:    "9:00",
-				End:      "12:00",
+				Start:    r.Clock(9, 0),
+				End:      r.Clock(12, 0),
 				Duration: 10,
 			},
 			DropOff: r.Destination{
 				Location: robson,
-				Start:    "9:00",
-				End:      "12:00",
+				Start:    r.Clock(9, 0),
+				End:      r.Clock(12, 0),
 				Duration: 10,
 			},
 		},
@@ -335,16 +335,16 @@ var pdpInput = r.PDPlan{
 		"vehicle_1": {
 			StartLocation: kingswayDepot,
 			EndLocation:   kingswayDepot,
-			ShiftStart:    "8:00",
-			ShiftEnd:      "12:00",
-			Capacity:      2,
+			ShiftStart:    r.Clock(8, 0),
+			ShiftEnd:      r.Clock(12, 0),
+			Capacity:      r.Units(2),
 		},
 		"vehicle_2": {
 			StartLocation: robsonDepot,
 			EndLocation:   kingswayDepot,
-			ShiftStart:    "8:00",
-			ShiftEnd:      "12:00",
-			Capacity:      1,
+			ShiftStart:    r.Clock(8, 0),
+			ShiftEnd:      r.Clock(12, 0),
+			Capacity:      r.Units(1),
 		},
 	},
 }
@@ -353,40 +353,40 @@ var pdpRoute1 = []r.Stop{
 	{
 		ID:          "depot",
 		Name:        "800 Kingsway",
-		ArrivalTime: "08:50",
+		ArrivalTime: r.Clock(8, 50),
 	},
 	{
 		ID:          "order_2",
 		Name:        "3780 Arbutus",
-		ArrivalTime: "09:00",
-		FinishTime:  "09:10",
+		ArrivalTime: r.Clock(9, 0),
+		FinishTime:  r.Clock(9, 10),
 		Type:        "pickup",
 	},
 	{
 		ID:          "order_1",
 		Name:        "3780 Arbutus",
-		ArrivalTime: "09:10",
-		FinishTime:  "09:20",
+		ArrivalTime: r.Clock(9, 10),
+		FinishTime:  r.Clock(9, 20),
 		Type:        "pickup",
 	},
 	{
 		ID:          "order_1",
 		Name:        "6800 Cambie",
-		ArrivalTime: "09:26",
-		FinishTime:  "09:36",
+		ArrivalTime: r.Clock(9, 26),
+		FinishTime:  r.Clock(9, 36),
 		Type:        "dropoff",
 	},
 	{
 		ID:          "order_2",
 		Name:        "800 Robson",
-		ArrivalTime: "09:45",
-		FinishTime:  "09:55",
+		ArrivalTime: r.Clock(9, 45),
+		FinishTime:  r.Clock(9, 55),
 		Type:        "dropoff",
 	},
 	{
 		ID:          "depot",
 		Name:        "800 Kingsway",
-		ArrivalTime: "10:02",
+		ArrivalTime: r.Clock(10, 2),
 	},
 }
 
@@ -394,12 +394,12 @@ var pdpRoute2 = []r.Stop{
 	{
 		ID:          "depot 2",
 		Name:        "800 Robson",
-		ArrivalTime: "08:00",
+		ArrivalTime: r.Clock(8, 0),
 	},
 	{
 		ID:          "depot",
 		Name:        "800 Kingsway",
-		ArrivalTime: "08:06",
+		ArrivalTime: r.Clock(8, 6),
 	},
 }
 
@@ -0,0 +1,44 @@
+package routific
+
+import "context"
+
+// Solver solves routing plans into schedules. It is implemented by Client,
+// which calls the Routific API, and by the offline solver of package local.
+type Solver interface {
+	SolveVRP(ctx context.Context, plan VRPlan) (Schedule, error)
+	SolvePDP(ctx context.Context, plan PDPlan) (Schedule, error)
+}
+
+// SolveVRP implements Solver by calling VRPContext.
+func (c *Client) SolveVRP(ctx context.Context, plan VRPlan) (Schedule, error) {
+	return c.VRPContext(ctx, plan)
+}
+
+// SolvePDP implements Solver by calling PDPContext.
+func (c *Client) SolvePDP(ctx context.Context, plan PDPlan) (Schedule, error) {
+	return c.PDPContext(ctx, plan)
+}
+
+// SolverFuncs adapts a pair of functions, e.g. closures over the package-level
+// VRP and PDP functions, to a Solver. A nil function fails with
+// ErrNotSupported.
+type SolverFuncs struct {
+	VRP func(ctx context.Context, plan VRPlan) (Schedule, error)
+	PDP func(ctx context.Context, plan PDPlan) (Schedule, error)
+}
+
+// SolveVRP implements Solver by calling f.VRP.
+func (f SolverFuncs) SolveVRP(ctx context.Context, plan VRPlan) (Schedule, error) {
+	if f.VRP == nil {
+		return Schedule{}, ErrNotSupported
+	}
+	return f.VRP(ctx, plan)
+}
+
+// SolvePDP implements Solver by calling f.PDP.
+func (f SolverFuncs) SolvePDP(ctx context.Context, plan PDPlan) (Schedule, error) {
+	if f.PDP == nil {
+		return Schedule{}, ErrNotSupported
+	}
+	return f.PDP(ctx, plan)
+}
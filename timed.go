@@ -0,0 +1,254 @@
+package routific
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// TimedStop is a Stop with absolute arrival and finish times. In JSON,
+// finish is left out if the stop has none.
+type TimedStop struct {
+	Stop
+	Arrival time.Time `json:"arrival"`
+	Finish  time.Time `json:"finish,omitempty"` // zero if Stop has no finish
+}
+
+// TimedSchedule is a Schedule whose stops are placed on a service date in a
+// time zone, e.g. for dispatching to drivers.
+type TimedSchedule struct {
+	Schedule
+	Date     time.Time              `json:"date"` // midnight of the service date
+	Routes   map[string][]TimedStop `json:"routes"`
+	location *time.Location
+}
+
+// timedStop holds the fields that TimedStop adds to Stop. Finish is a
+// pointer, as omitempty does not leave out a zero time.Time.
+type timedStop struct {
+	Arrival time.Time  `json:"arrival"`
+	Finish  *time.Time `json:"finish,omitempty"`
+}
+
+// MarshalJSON writes the Stop fields together with the times.
+func (t TimedStop) MarshalJSON() ([]byte, error) {
+
+	times := timedStop{Arrival: t.Arrival}
+	if !t.Finish.IsZero() {
+		times.Finish = &t.Finish
+	}
+
+	return mergeJSON(nil, t.Stop, times)
+}
+
+// UnmarshalJSON reads what MarshalJSON writes.
+func (t *TimedStop) UnmarshalJSON(data []byte) error {
+
+	var times timedStop
+	if err := json.Unmarshal(data, &times); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &t.Stop); err != nil {
+		return err
+	}
+
+	t.Arrival, t.Finish = times.Arrival, time.Time{}
+	if times.Finish != nil {
+		t.Finish = *times.Finish
+	}
+	t.Stop.Extra = pruneExtra(t.Stop.Extra, times)
+	return nil
+}
+
+// timedSchedule holds the fields that TimedSchedule adds to Schedule.
+type timedSchedule struct {
+	Date   time.Time              `json:"date"`
+	Routes map[string][]TimedStop `json:"routes"`
+}
+
+// MarshalJSON writes the Schedule fields together with the date and routes.
+func (t TimedSchedule) MarshalJSON() ([]byte, error) {
+	return mergeJSON(nil, t.Schedule, timedSchedule{t.Date, t.Routes})
+}
+
+// UnmarshalJSON reads what MarshalJSON writes, in the time zone of the
+// date.
+func (t *TimedSchedule) UnmarshalJSON(data []byte) error {
+
+	var timed timedSchedule
+	if err := json.Unmarshal(data, &timed); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &t.Schedule); err != nil {
+		return err
+	}
+
+	t.Date, t.Routes = timed.Date, timed.Routes
+	t.location = timed.Date.Location()
+	t.Schedule.Extra = pruneExtra(t.Schedule.Extra, timed)
+	return nil
+}
+
+// pruneExtra removes the fields of the struct v from extra.
+func pruneExtra(extra map[string]json.RawMessage, v interface{}) map[string]json.RawMessage {
+
+	dropFields(extra, v)
+	if len(extra) == 0 {
+		return nil
+	}
+
+	return extra
+}
+
+// Materialize places every stop of the schedule on the given service date in
+// loc. Routes running past midnight continue on the next day: a stop whose
+// time is earlier than the one before it is taken to be a day later. A nil
+// loc means UTC.
+func (s Schedule) Materialize(date time.Time, loc *time.Location) TimedSchedule {
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	y, m, d := date.Date()
+
+	t := TimedSchedule{
+		Schedule: s,
+		Date:     time.Date(y, m, d, 0, 0, 0, 0, loc),
+		Routes:   make(map[string][]TimedStop, len(s.Solution)),
+		location: loc,
+	}
+
+	for vehicle, stops := range s.Solution {
+		route := make([]TimedStop, len(stops))
+		day := 0
+		last := ClockTime(-1)
+
+		// at returns c on the current day, moving on a day if c is earlier
+		// than the previous time of the route
+		at := func(c ClockTime) time.Time {
+			if c < last {
+				day++
+			}
+			last = c
+			return c.On(t.Date, loc).AddDate(0, 0, day)
+		}
+
+		for i, stop := range stops {
+			route[i] = TimedStop{Stop: stop, Arrival: at(stop.ArrivalTime)}
+			if stop.HasFinish() {
+				route[i].Finish = at(stop.FinishTime)
+			}
+		}
+
+		t.Routes[vehicle] = route
+	}
+
+	return t
+}
+
+// Location returns the time zone of the schedule.
+func (t TimedSchedule) Location() *time.Location {
+
+	if t.location == nil {
+		return time.UTC
+	}
+
+	return t.location
+}
+
+// RemainingVRP returns the part of plan that is left to do at the given
+// time, for re-optimising a schedule during the day:
+//   - visits that no vehicle has arrived at by now, including unserved ones;
+//   - vehicles whose route is not finished, starting from the location of
+//     their latest visit, no earlier than now or the end of the stop they
+//     are at, without the breaks they have started.
+//
+// Vehicles that have no route in the schedule are kept unchanged.
+func (t TimedSchedule) RemainingVRP(plan VRPlan, now time.Time) VRPlan {
+
+	remaining := VRPlan{
+		Visits:  map[string]Visit{},
+		Fleet:   map[string]Vehicle{},
+		Options: plan.Options,
+	}
+
+	done := map[string]bool{}
+
+	for _, key := range sortedKeys(t.Routes) {
+		route := t.Routes[key]
+		vehicle, ok := plan.Fleet[key]
+		if !ok || len(route) == 0 {
+			continue
+		}
+
+		// Latest stop reached by now
+		latest := sort.Search(len(route), func(i int) bool {
+			return route[i].Arrival.After(now)
+		}) - 1
+
+		for _, stop := range route[:latest+1] {
+			done[stop.ID] = true
+		}
+
+		if latest == len(route)-1 && len(route) > 1 {
+			continue // back at the depot
+		}
+
+		if latest >= 0 {
+			vehicle.Breaks = remainingBreaks(vehicle.Breaks, route[:latest+1])
+
+			// Breaks are taken where the vehicle is, so start from the
+			// latest visit
+			for i := latest; i >= 0; i-- {
+				if visit, ok := plan.Visits[route[i].ID]; ok && !route[i].Break {
+					vehicle.StartLocation = visit.Location
+					vehicle.StartLocation.ID = route[i].ID
+					break
+				}
+			}
+			// A vehicle in the middle of a visit or break leaves once done
+			leave := now
+			if stop := route[latest]; stop.HasFinish() && stop.Finish.After(now) {
+				leave = stop.Finish
+			}
+			if clock := ClockOf(leave.In(t.Location())); clock > vehicle.ShiftStart {
+				vehicle.ShiftStart = clock
+			}
+		}
+
+		remaining.Fleet[key] = vehicle
+	}
+
+	for key, vehicle := range plan.Fleet {
+		if _, ok := t.Routes[key]; !ok {
+			remaining.Fleet[key] = vehicle
+		}
+	}
+
+	for key, visit := range plan.Visits {
+		if !done[key] {
+			remaining.Visits[key] = visit
+		}
+	}
+
+	return remaining
+}
+
+// remainingBreaks returns the breaks that are not among the reached stops.
+func remainingBreaks(breaks []Break, reached []TimedStop) []Break {
+
+	var left []Break
+	for _, b := range breaks {
+		taken := false
+		for _, stop := range reached {
+			if stop.Break && stop.ID == b.ID {
+				taken = true
+			}
+		}
+		if !taken {
+			left = append(left, b)
+		}
+	}
+
+	return left
+}
@@ -0,0 +1,213 @@
+package routific_test
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// timed_test checks placing schedules on a service date and time zone.
+// Test data is defined in setup_test.
+
+func TestMaterialize(t *testing.T) {
+
+	loc := time.FixedZone("PDT", -7*3600)
+	date := time.Date(2022, 6, 1, 15, 0, 0, 0, time.UTC)
+
+	timed := pdpOutput.Materialize(date, loc)
+	assert.Equal(t, time.Date(2022, 6, 1, 0, 0, 0, 0, loc), timed.Date)
+	assert.Equal(t, loc, timed.Location())
+	assert.Equal(t, pdpOutput, timed.Schedule)
+
+	route := timed.Routes["vehicle_1"]
+	assert.Len(t, route, len(pdpRoute1))
+	assert.Equal(t, pdpRoute1[1], route[1].Stop)
+	assert.Equal(t, time.Date(2022, 6, 1, 9, 0, 0, 0, loc), route[1].Arrival)
+	assert.Equal(t, time.Date(2022, 6, 1, 9, 10, 0, 0, loc), route[1].Finish)
+	assert.True(t, route[0].Finish.IsZero())
+
+	// The depot has no finish in JSON either
+	var depot, visit map[string]interface{}
+	b, err := json.Marshal(route[0])
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(b, &depot))
+	assert.NotContains(t, depot, "finish")
+	assert.Contains(t, depot, "arrival")
+
+	b, err = json.Marshal(route[1])
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(b, &visit))
+	assert.Equal(t, "2022-06-01T09:10:00-07:00", visit["finish"])
+
+	var again r.TimedStop
+	assert.Nil(t, json.Unmarshal(b, &again))
+	assert.True(t, route[1].Finish.Equal(again.Finish))
+
+	// A nil location is UTC
+	timed = pdpOutput.Materialize(date, nil)
+	assert.Equal(t, time.UTC, timed.Location())
+	assert.Equal(t, time.Date(2022, 6, 1, 9, 0, 0, 0, time.UTC),
+		timed.Routes["vehicle_1"][1].Arrival)
+}
+
+func TestMaterializeOvernight(t *testing.T) {
+
+	schedule := r.Schedule{
+		Solution: map[string]r.Stops{
+			"night_shift": {
+				{ID: "depot", ArrivalTime: r.Clock(22, 0)},
+				{ID: "order_1", ArrivalTime: r.Clock(23, 40),
+					FinishTime: r.Clock(0, 10)},
+				{ID: "order_2", ArrivalTime: r.Clock(0, 30),
+					FinishTime: r.Clock(0, 45)},
+				{ID: "depot", ArrivalTime: r.Clock(1, 15)},
+			},
+		},
+	}
+
+	date := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+	route := schedule.Materialize(date, time.UTC).Routes["night_shift"]
+
+	assert.Equal(t, time.Date(2022, 12, 31, 22, 0, 0, 0, time.UTC), route[0].Arrival)
+	assert.Equal(t, time.Date(2022, 12, 31, 23, 40, 0, 0, time.UTC), route[1].Arrival)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 10, 0, 0, time.UTC), route[1].Finish)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 30, 0, 0, time.UTC), route[2].Arrival)
+	assert.Equal(t, time.Date(2023, 1, 1, 1, 15, 0, 0, time.UTC), route[3].Arrival)
+}
+
+func TestMaterializeMidnight(t *testing.T) {
+
+	const route = `[
+		{"location_id": "depot", "arrival_time": "23:00"},
+		{"location_id": "order_1", "arrival_time": "23:40", "finish_time": "00:00"},
+		{"location_id": "depot", "arrival_time": "00:30"}
+	]`
+	var stops r.Stops
+	assert.Nil(t, json.Unmarshal([]byte(route), &stops))
+	assert.True(t, stops[1].HasFinish())
+	assert.False(t, stops[2].HasFinish())
+
+	b, err := json.Marshal(stops[1])
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"finish_time":"00:00"`)
+
+	schedule := r.Schedule{Solution: map[string]r.Stops{"night_shift": stops}}
+	date := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+	timed := schedule.Materialize(date, time.UTC).Routes["night_shift"]
+
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), timed[1].Finish)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 30, 0, 0, time.UTC), timed[2].Arrival)
+	assert.True(t, timed[2].Finish.IsZero())
+}
+
+func TestRemainingVRP(t *testing.T) {
+
+	schedule := r.Schedule{
+		Solution: map[string]r.Stops{
+			"vehicle_1": {
+				{ID: "depot", ArrivalTime: r.Clock(8, 0)},
+				{ID: "order_3", ArrivalTime: r.Clock(8, 20), FinishTime: r.Clock(8, 30)},
+				{ID: "order_2", ArrivalTime: r.Clock(8, 50), FinishTime: r.Clock(9, 0)},
+				{ID: "order_1", ArrivalTime: r.Clock(9, 15), FinishTime: r.Clock(9, 25)},
+				{ID: "depot", ArrivalTime: r.Clock(9, 45)},
+			},
+		},
+	}
+
+	plan := vrpInput
+	plan.Fleet = map[string]r.Vehicle{
+		"vehicle_1": {
+			StartLocation: kingswayDepot,
+			EndLocation:   kingswayDepot,
+			ShiftStart:    r.Clock(8, 0),
+		},
+		"spare": {StartLocation: robsonDepot},
+	}
+
+	date := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2022, 6, 1, 8, 35, 0, 0, time.UTC)
+	remaining := schedule.Materialize(date, time.UTC).RemainingVRP(plan, now)
+
+	assert.Equal(t, map[string]r.Visit{
+		"order_1": {Location: cambie},
+		"order_2": {Location: arbutus},
+	}, remaining.Visits)
+
+	robsonStop := robson
+	robsonStop.ID = "order_3"
+	assert.Equal(t, map[string]r.Vehicle{
+		"vehicle_1": {
+			StartLocation: robsonStop,
+			EndLocation:   kingswayDepot,
+			ShiftStart:    r.Clock(8, 35),
+		},
+		"spare": {StartLocation: robsonDepot},
+	}, remaining.Fleet)
+	assert.Nil(t, remaining.Validate())
+
+	// In the middle of serving order_2, the vehicle leaves when done
+	serving := time.Date(2022, 6, 1, 8, 55, 0, 0, time.UTC)
+	remaining = schedule.Materialize(date, time.UTC).RemainingVRP(plan, serving)
+	assert.Equal(t, []string{"order_1"}, keys(remaining.Visits))
+	assert.Equal(t, "order_2", remaining.Fleet["vehicle_1"].StartLocation.ID)
+	assert.Equal(t, r.Clock(9, 0), remaining.Fleet["vehicle_1"].ShiftStart)
+
+	// Once back at the depot, the vehicle is done for the day
+	late := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	remaining = schedule.Materialize(date, time.UTC).RemainingVRP(plan, late)
+	assert.Empty(t, remaining.Visits)
+	assert.Equal(t, []string{"spare"}, keys(remaining.Fleet))
+}
+
+// keys returns the keys of m, sorted.
+func keys[T any](m map[string]T) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestRemainingVRPBreaks(t *testing.T) {
+
+	schedule := r.Schedule{
+		Solution: map[string]r.Stops{
+			"vehicle_1": {
+				{ID: "depot", ArrivalTime: r.Clock(8, 0)},
+				{ID: "order_3", ArrivalTime: r.Clock(8, 20), FinishTime: r.Clock(8, 30)},
+				{ID: "lunch", ArrivalTime: r.Clock(8, 30), FinishTime: r.Clock(9, 0), Break: true},
+				{ID: "order_2", ArrivalTime: r.Clock(9, 20), FinishTime: r.Clock(9, 30)},
+				{ID: "depot", ArrivalTime: r.Clock(9, 45)},
+			},
+		},
+	}
+
+	fuel := r.Break{ID: "fuel", Start: r.Clock(11, 0), End: r.Clock(11, 15)}
+	plan := vrpInput
+	plan.Fleet = map[string]r.Vehicle{
+		"vehicle_1": {
+			StartLocation: kingswayDepot,
+			EndLocation:   kingswayDepot,
+			Breaks: []r.Break{
+				{ID: "lunch", Start: r.Clock(8, 30), End: r.Clock(9, 0)},
+				fuel,
+			},
+		},
+	}
+
+	// During the lunch break, the vehicle is still at order_3
+	date := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2022, 6, 1, 8, 45, 0, 0, time.UTC)
+	remaining := schedule.Materialize(date, time.UTC).RemainingVRP(plan, now)
+
+	vehicle := remaining.Fleet["vehicle_1"]
+	assert.Equal(t, "order_3", vehicle.StartLocation.ID)
+	assert.Equal(t, robson.Latitude, vehicle.StartLocation.Latitude)
+	assert.Equal(t, []r.Break{fuel}, vehicle.Breaks)
+	assert.Equal(t, r.Clock(9, 0), vehicle.ShiftStart) // after lunch
+}
@@ -1,9 +1,11 @@
 package routific
 
+import "encoding/json"
+
 // TimeWindow defines the time window when a location can be visited.
 type TimeWindow struct {
-	Start string `json:"start,omitempty"` // "hh:mm"
-	End   string `json:"end,omitempty"`   // "hh:mm"
+	Start ClockTime `json:"start,omitempty"`
+	End   ClockTime `json:"end,omitempty"`
 }
 
 // Location describes the GPS coordinate of a location.
@@ -18,10 +20,10 @@ type Location struct {
 // See [Visits]: https://docs.routific.com/reference/input
 type Visit struct {
 	Location    Location     `json:"location,omitempty"`
-	Start       string       `json:"start,omitempty"`    // "hh:mm"
-	End         string       `json:"end,omitempty"`      // "hh:mm"
-	Duration    uint8        `json:"duration,omitempty"` // minutes
-	Load        interface{}  `json:"load,omitempty"`
+	Start       ClockTime    `json:"start,omitempty"`
+	End         ClockTime    `json:"end,omitempty"`
+	Duration    float32      `json:"duration,omitempty"` // minutes
+	Load        Load         `json:"load,omitempty"`
 	Type        string       `json:"type,omitempty"`
 	Priority    string       `json:"priority,omitempty"`
 	TimeWindows []TimeWindow `json:"time_windows,omitempty"`
@@ -32,16 +34,16 @@ type Visit struct {
 // Vehicle describes the vehicle or driver in the fleet.
 // See [Fleet]: https://docs.routific.com/reference/fleet
 type Vehicle struct {
-	StartLocation Location    `json:"start_location,omitempty"`
-	EndLocation   Location    `json:"end_location,omitempty"`
-	ShiftStart    string      `json:"shift_start,omitempty"` // "hh:mm"
-	ShiftEnd      string      `json:"shift_end,omitempty"`   // "hh:mm"
-	Capacity      uint8       `json:"capacity,omitempty"`
-	Type          string      `json:"type,omitempty"`
-	Speed         string      `json:"speed,omitempty"`
-	StrictStart   bool        `json:"strict_start,omitempty"`
-	MinVisits     uint8       `json:"min_visits,omitempty"`
-	Breaks        interface{} `json:"breaks,omitempty"`
+	StartLocation Location  `json:"start_location,omitempty"`
+	EndLocation   Location  `json:"end_location,omitempty"`
+	ShiftStart    ClockTime `json:"shift_start,omitempty"`
+	ShiftEnd      ClockTime `json:"shift_end,omitempty"`
+	Capacity      Load      `json:"capacity,omitempty"`
+	Type          string    `json:"type,omitempty"`
+	Speed         string    `json:"speed,omitempty"`
+	StrictStart   bool      `json:"strict_start,omitempty"`
+	MinVisits     int       `json:"min_visits,omitempty"`
+	Breaks        []Break   `json:"breaks,omitempty"`
 }
 
 // VRPlan is the vehicle routing plan that we want Routific to solve / optimise.
@@ -53,16 +55,16 @@ type VRPlan struct {
 
 // Destination describes the location for pickup and dropoff.
 type Destination struct {
-	Location Location `json:"location"`
-	Start    string   `json:"start,omitempty"`    // "hh:mm"
-	End      string   `json:"end,omitempty"`      // "hh:mm"
-	Duration uint8    `json:"duration,omitempty"` // minutes
+	Location Location  `json:"location"`
+	Start    ClockTime `json:"start,omitempty"`
+	End      ClockTime `json:"end,omitempty"`
+	Duration float32   `json:"duration,omitempty"` // minutes
 }
 
 // PickDropOrder describes the targeted pickup and dropoff.
 // See [Orders]: https://docs.routific.com/reference/defining-orders
 type PickDropOrder struct {
-	Load    uint8       `json:"load,omitempty"`
+	Load    Load        `json:"load,omitempty"`
 	PickUp  Destination `json:"pickup,omitempty"`
 	DropOff Destination `json:"dropoff,omitempty"`
 	Type    []string    `json:"type,omitempty"`
@@ -78,13 +80,23 @@ type PDPlan struct {
 
 // Stop defines the stop during the route for pickup or dropoff.
 type Stop struct {
-	ID          string  `json:"location_id,omitempty"`
-	Name        string  `json:"location_name,omitempty"`
-	ArrivalTime string  `json:"arrival_time,omitempty"` // "hh:mm"
-	FinishTime  string  `json:"finish_time,omitempty"`  // "hh:mm"
-	Type        string  `json:"type,omitempty"`
-	Late        bool    `json:"too_late,omitempty"`
-	LateBy      float32 `json:"late_by,omitempty"`
+	ID          string    `json:"location_id,omitempty"`
+	Name        string    `json:"location_name,omitempty"`
+	ArrivalTime ClockTime `json:"arrival_time"` // always set, even to 00:00
+	FinishTime  ClockTime `json:"finish_time,omitempty"`
+	Type        string    `json:"type,omitempty"`
+	Late        bool      `json:"too_late,omitempty"`
+	LateBy      float32   `json:"late_by,omitempty"`
+	Break       bool      `json:"break,omitempty"`     // a driver break, see Break
+	Distance    float32   `json:"distance,omitempty"`  // km from the previous stop
+	IdleTime    float32   `json:"idle_time,omitempty"` // minutes waiting
+
+	// FinishAtMidnight is set for a stop that finishes at 00:00, which a
+	// zero FinishTime cannot tell from no finish time. See HasFinish.
+	FinishAtMidnight bool `json:"-"`
+
+	// Extra holds the output fields that Stop does not model, as returned.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Stops defines the order of stops.
@@ -98,31 +110,38 @@ type VehicleOvertime map[string]float32
 // requested / input plan.
 // See [Output]: https://docs.routific.com/reference/output
 type Schedule struct {
-	Status        string            `json:"status"`
-	TravelTime    float32           `json:"total_travel_time"` // minutes
-	IdleTime      float32           `json:"total_idle_time"`   // minutes
-	Fitness       uint8             `json:"fitness,omitempty"`
-	NumUnserved   uint8             `json:"num_unserved"`
-	Unserved      map[string]string `json:"unserved"`
-	Solution      map[string]Stops  `json:"solution"`
-	NumLateVisits uint8             `json:"num_late_visits,omitempty"`
-	TotalLateness float32           `json:"total_visit_lateness,omitempty"` // minutes
-	Overtime      VehicleOvertime   `json:"vehicle_overtime,omitempty"`
-	TotalOvertime float32           `json:"total_overtime,omitempty"` // minutes
+	Status        string               `json:"status"`
+	TravelTime    float32              `json:"total_travel_time"` // minutes
+	IdleTime      float32              `json:"total_idle_time"`   // minutes
+	Fitness       float32              `json:"fitness,omitempty"`
+	NumUnserved   int                  `json:"num_unserved"`
+	Unserved      map[string]string    `json:"unserved"`
+	Solution      map[string]Stops     `json:"solution"`
+	NumLateVisits int                  `json:"num_late_visits,omitempty"`
+	TotalLateness float32              `json:"total_visit_lateness,omitempty"` // minutes
+	Overtime      VehicleOvertime      `json:"vehicle_overtime,omitempty"`
+	TotalOvertime float32              `json:"total_overtime,omitempty"`     // minutes
+	TotalDistance float32              `json:"total_distance,omitempty"`     // km
+	WorkingTime   float32              `json:"total_working_time,omitempty"` // minutes
+	Polylines     map[string]Polylines `json:"polylines,omitempty"`          // by vehicle
+
+	// Extra holds the output fields that Schedule does not model, as
+	// returned.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Options tweak how the Routific Engine performs the optimisation.
 // See [Input Options]: https://docs.routific.com/reference/options
 type Options struct {
 	Traffic                 string  `json:"traffic,omitempty"`
-	MinVisitsPerVehicle     uint8   `json:"min_visits_per_vehicle,omitempty"`
+	MinVisitsPerVehicle     int     `json:"min_visits_per_vehicle,omitempty"`
 	Balance                 bool    `json:"balance,omitempty"`
 	VisitBalanceCoefficient float32 `json:"visit_balance_coefficient,omitempty"`
 	MinVehicles             bool    `json:"min_vehicles,omitempty"`
 	ShortestDistance        bool    `json:"shortest_distance,omitempty"`
-	SquashDurations         uint8   `json:"squash_durations,omitempty"`
-	MaxVehicleOvertime      uint8   `json:"max_vehicle_overtime,omitempty"` // minutes
-	MaxVisitLateness        uint8   `json:"max_visit_lateness,omitempty"`   // minutes
+	SquashDurations         float32 `json:"squash_durations,omitempty"`     // minutes
+	MaxVehicleOvertime      float32 `json:"max_vehicle_overtime,omitempty"` // minutes
+	MaxVisitLateness        float32 `json:"max_visit_lateness,omitempty"`   // minutes
 	Polylines               bool    `json:"polylines,omitempty"`
 	AvoidTolls              bool    `json:"avoid_tolls,omitempty"`
 	GeoCoder                string  `json:"geocoder,omitempty"`
@@ -2,6 +2,7 @@ package routific_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	r "github.com/slamethendry/routific"
@@ -65,3 +66,53 @@ func TestParseOptionsInput(t *testing.T) {
 	// Compare the JSON conversion vs manually created object
 	assert.Equal(t, options, optionsInput)
 }
+
+func TestParseLargePlan(t *testing.T) {
+
+	// A plan with values beyond 255: 4-hour service durations, and options
+	// and counts of a 300-stop day
+	visits := map[string]r.Visit{}
+	for i := 0; i < 300; i++ {
+		visits[fmt.Sprintf("order_%d", i)] = r.Visit{
+			Location: r.Location{Latitude: 49.2 + float32(i)/1000, Longitude: -123.1},
+			Duration: 240,
+			Load:     r.Units(1),
+		}
+	}
+	plan := r.VRPlan{
+		Visits: visits,
+		Fleet: map[string]r.Vehicle{
+			"vehicle_1": {StartLocation: kingswayDepot, MinVisits: 280},
+		},
+		Options: r.Options{
+			MinVisitsPerVehicle: 280,
+			SquashDurations:     2.5,
+			MaxVehicleOvertime:  300,
+			MaxVisitLateness:    480,
+		},
+	}
+
+	b, err := json.Marshal(plan)
+	assert.Nil(t, err)
+
+	var got r.VRPlan
+	assert.Nil(t, json.Unmarshal(b, &got))
+	assert.Equal(t, plan, got)
+	assert.Equal(t, float32(240), got.Visits["order_299"].Duration)
+
+	var sched r.Schedule
+	err = json.Unmarshal([]byte(`{
+		"status": "success",
+		"total_travel_time": 1520.5,
+		"total_idle_time": 0,
+		"fitness": 0.87,
+		"num_unserved": 300,
+		"unserved": {},
+		"solution": {},
+		"num_late_visits": 256
+	}`), &sched)
+	assert.Nil(t, err)
+	assert.Equal(t, 300, sched.NumUnserved)
+	assert.Equal(t, 256, sched.NumLateVisits)
+	assert.Equal(t, float32(0.87), sched.Fitness)
+}
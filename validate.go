@@ -0,0 +1,271 @@
+package routific
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldError describes one problem in a plan.
+type FieldError struct {
+	Section string // "visits", "fleet", or "options"
+	Key     string // visit, order, or vehicle key; empty for a whole section
+	Field   string // path within the entry, e.g. "pickup.location.lat"
+	Msg     string
+}
+
+func (e FieldError) Error() string {
+
+	path := e.Section
+	if e.Key != "" {
+		path += "[" + e.Key + "]"
+	}
+	if e.Field != "" {
+		path += "." + e.Field
+	}
+
+	return path + ": " + e.Msg
+}
+
+// ValidationError lists every problem found in a plan. It matches
+// ErrInvalidInput with errors.Is.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+
+	return fmt.Sprintf("routific: invalid plan: %s", strings.Join(msgs, "; "))
+}
+
+// Unwrap returns ErrInvalidInput.
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// Validate checks the plan for mistakes that Routific would reject, and
+// returns a *ValidationError listing all of them, or nil.
+func (p VRPlan) Validate() error {
+
+	var v validator
+
+	if len(p.Visits) == 0 {
+		v.add("visits", "", "", "no visits")
+	}
+	for _, key := range sortedKeys(p.Visits) {
+		visit := p.Visits[key]
+		v.location("visits", key, "location", visit.Location)
+		v.window("visits", key, "", visit.Start, visit.End)
+		v.load("visits", key, "load", visit.Load)
+		for i, tw := range visit.TimeWindows {
+			field := fmt.Sprintf("time_windows[%d].", i)
+			v.window("visits", key, field, tw.Start, tw.End)
+		}
+	}
+
+	v.fleet(p.Fleet)
+	v.duplicates(sortedKeys(p.Visits), p.Fleet)
+
+	return v.err()
+}
+
+// Validate checks the plan for mistakes that Routific would reject, and
+// returns a *ValidationError listing all of them, or nil.
+func (p PDPlan) Validate() error {
+
+	var v validator
+
+	if len(p.Visits) == 0 {
+		v.add("visits", "", "", "no orders")
+	}
+	for _, key := range sortedKeys(p.Visits) {
+		order := p.Visits[key]
+		v.destination(key, "pickup", order.PickUp)
+		v.destination(key, "dropoff", order.DropOff)
+		v.load("visits", key, "load", order.Load)
+	}
+
+	v.fleet(p.Fleet)
+	v.duplicates(sortedKeys(p.Visits), p.Fleet)
+
+	return v.err()
+}
+
+// validator collects the problems of a plan.
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) add(section, key, field, msg string) {
+	v.errs = append(v.errs, FieldError{section, key, field, msg})
+}
+
+func (v *validator) err() error {
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Errors: v.errs}
+}
+
+func (v *validator) destination(key, field string, d Destination) {
+
+	if d == (Destination{}) {
+		v.add("visits", key, field, "missing")
+		return
+	}
+
+	v.location("visits", key, field+".location", d.Location)
+	v.window("visits", key, field+".", d.Start, d.End)
+}
+
+func (v *validator) fleet(fleet map[string]Vehicle) {
+
+	if len(fleet) == 0 {
+		v.add("fleet", "", "", "no vehicles")
+	}
+
+	for _, key := range sortedKeys(fleet) {
+		vehicle := fleet[key]
+		v.location("fleet", key, "start_location", vehicle.StartLocation)
+		if vehicle.EndLocation != (Location{}) {
+			v.location("fleet", key, "end_location", vehicle.EndLocation)
+		}
+		v.timeRange("fleet", key, "shift_start", "shift_end",
+			vehicle.ShiftStart, vehicle.ShiftEnd)
+		v.load("fleet", key, "capacity", vehicle.Capacity)
+		for i, b := range vehicle.Breaks {
+			v.brk(key, fmt.Sprintf("breaks[%d]", i), b)
+		}
+	}
+}
+
+// brk checks a break of the vehicle with the given key.
+func (v *validator) brk(key, field string, b Break) {
+
+	if b.ID == "" {
+		v.add("fleet", key, field+".id", "missing")
+	}
+	if b.Start == 0 && b.End == 0 {
+		v.add("fleet", key, field, "no start or end")
+		return
+	}
+	v.window("fleet", key, field+".", b.Start, b.End)
+	if b.Duration < 0 || b.Start.Before(b.End) &&
+		b.Duration > float32(b.End-b.Start) {
+		v.add("fleet", key, field+".duration",
+			fmt.Sprintf("duration %g does not fit between %s and %s",
+				b.Duration, b.Start, b.End))
+	}
+	if b.Location != nil {
+		v.location("fleet", key, field+".location", *b.Location)
+	}
+}
+
+// duplicates reports visit keys that are also used as the ID of a depot or
+// a break.
+func (v *validator) duplicates(visitKeys []string, fleet map[string]Vehicle) {
+
+	visits := map[string]bool{}
+	for _, key := range visitKeys {
+		visits[key] = true
+	}
+
+	for _, key := range sortedKeys(fleet) {
+		vehicle := fleet[key]
+		if id := vehicle.StartLocation.ID; id != "" && visits[id] {
+			v.add("fleet", key, "start_location.id",
+				fmt.Sprintf("ID %q is also used by a visit", id))
+		}
+		if id := vehicle.EndLocation.ID; id != "" && visits[id] {
+			v.add("fleet", key, "end_location.id",
+				fmt.Sprintf("ID %q is also used by a visit", id))
+		}
+		for i, b := range vehicle.Breaks {
+			if b.ID != "" && visits[b.ID] {
+				v.add("fleet", key, fmt.Sprintf("breaks[%d].id", i),
+					fmt.Sprintf("ID %q is also used by a visit", b.ID))
+			}
+		}
+	}
+}
+
+func (v *validator) location(section, key, field string, loc Location) {
+
+	if loc.Latitude == 0 && loc.Longitude == 0 {
+		v.add(section, key, field, "no coordinates")
+		return
+	}
+	if loc.Latitude < -90 || loc.Latitude > 90 {
+		v.add(section, key, field+".lat",
+			fmt.Sprintf("latitude %g out of range", loc.Latitude))
+	}
+	if loc.Longitude < -180 || loc.Longitude > 180 {
+		v.add(section, key, field+".lng",
+			fmt.Sprintf("longitude %g out of range", loc.Longitude))
+	}
+}
+
+func (v *validator) load(section, key, field string, l Load) {
+
+	if _, ok := l[""]; ok && len(l) > 1 {
+		v.add(section, key, field,
+			"mixes a single quantity with named dimensions")
+	}
+	for _, dim := range l.Dimensions() {
+		if l[dim] < 0 {
+			f := field
+			if dim != "" {
+				f += "." + dim
+			}
+			v.add(section, key, f, fmt.Sprintf("negative quantity %g", l[dim]))
+		}
+	}
+}
+
+// window checks the start and end fields under prefix, e.g. "pickup.".
+func (v *validator) window(section, key, prefix string, start, end ClockTime) {
+	v.timeRange(section, key, prefix+"start", prefix+"end", start, end)
+}
+
+// timeRange checks an optional range of times, where zero means unset.
+func (v *validator) timeRange(
+	section, key, startField, endField string,
+	start, end ClockTime,
+) {
+
+	okStart := v.clock(section, key, startField, start)
+	okEnd := v.clock(section, key, endField, end)
+
+	if okStart && okEnd && start != 0 && end != 0 && !start.Before(end) {
+		v.add(section, key, startField,
+			fmt.Sprintf("%s is not before %s %s", start, endField, end))
+	}
+}
+
+func (v *validator) clock(section, key, field string, t ClockTime) bool {
+
+	if !t.Valid() {
+		v.add(section, key, field, fmt.Sprintf("invalid time %s", t))
+		return false
+	}
+
+	return true
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,109 @@
+package routific_test
+
+import (
+	"errors"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/slamethendry/routific/routifictest"
+	"github.com/stretchr/testify/assert"
+)
+
+// validate_test checks that plan validation reports every problem with its
+// key and field path.
+// Test data is defined in setup_test.
+
+func TestValidateValidPlans(t *testing.T) {
+	assert.Nil(t, vrpInput.Validate())
+	assert.Nil(t, pdpInput.Validate())
+}
+
+func TestValidateVRPlan(t *testing.T) {
+
+	plan := r.VRPlan{
+		Visits: map[string]r.Visit{
+			"order_1": {Location: r.Location{Name: "nowhere"}},
+			"order_2": {Location: arbutus, Start: r.Clock(12, 0), End: r.Clock(9, 0)},
+			"order_3": {
+				Location: robson,
+				TimeWindows: []r.TimeWindow{
+					{Start: r.Clock(9, 0), End: r.Clock(10, 0)},
+					{Start: r.Clock(25, 0), End: r.Clock(9, 0)},
+				},
+			},
+			"depot": {Location: cambie},
+		},
+		Fleet: map[string]r.Vehicle{
+			"vehicle_1": {
+				StartLocation: kingswayDepot,
+				ShiftStart:    r.Clock(8, 0),
+				ShiftEnd:      r.Clock(8, 0),
+			},
+			"vehicle_2": {
+				EndLocation: r.Location{Latitude: 91, Longitude: 10},
+			},
+		},
+	}
+
+	err := plan.Validate()
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+
+	var v *r.ValidationError
+	assert.True(t, errors.As(err, &v))
+
+	var got []string
+	for _, fe := range v.Errors {
+		got = append(got, fe.Error())
+	}
+	assert.Equal(t, []string{
+		"visits[order_1].location: no coordinates",
+		`visits[order_2].start: 12:00 is not before end 09:00`,
+		"visits[order_3].time_windows[1].start: invalid time 25:00",
+		"fleet[vehicle_1].shift_start: 08:00 is not before shift_end 08:00",
+		"fleet[vehicle_2].start_location: no coordinates",
+		"fleet[vehicle_2].end_location.lat: latitude 91 out of range",
+		`fleet[vehicle_1].start_location.id: ID "depot" is also used by a visit`,
+	}, got)
+
+	assert.Equal(t, r.FieldError{
+		Section: "visits",
+		Key:     "order_2",
+		Field:   "start",
+		Msg:     "12:00 is not before end 09:00",
+	}, v.Errors[1])
+}
+
+func TestValidatePDPlan(t *testing.T) {
+
+	plan := r.PDPlan{
+		Visits: map[string]r.PickDropOrder{
+			"order_1": {
+				PickUp: r.Destination{Location: arbutus},
+				Load:   r.Load{"weight": -1},
+			},
+		},
+	}
+
+	var v *r.ValidationError
+	assert.True(t, errors.As(plan.Validate(), &v))
+	assert.Equal(t, []r.FieldError{
+		{Section: "visits", Key: "order_1", Field: "dropoff", Msg: "missing"},
+		{Section: "visits", Key: "order_1", Field: "load.weight",
+			Msg: "negative quantity -1"},
+		{Section: "fleet", Msg: "no vehicles"},
+	}, v.Errors)
+}
+
+func TestValidateBeforePost(t *testing.T) {
+
+	srv := routifictest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.Client(r.WithValidation()).PDP(r.PDPlan{})
+	assert.True(t, errors.Is(err, r.ErrInvalidInput))
+	assert.Empty(t, srv.Requests())
+
+	_, err = srv.Client(r.WithValidation()).VRP(vrpInput)
+	assert.Nil(t, err)
+	assert.Len(t, srv.Requests(), 1)
+}
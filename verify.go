@@ -0,0 +1,560 @@
+package routific
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// rounding is the error of a time rounded to the minute, as Routific's are.
+const rounding = 0.5
+
+// Violation describes one way in which a schedule breaks its plan.
+type Violation struct {
+	// Kind is one of "missing", "duplicate", "unserved", "unknown",
+	// "vehicle", "type", "time_window", "duration", "shift", "break",
+	// "capacity", "order", "travel", or "total".
+	Kind    string
+	Vehicle string // vehicle key; empty for the whole schedule
+	Stop    int    // index of the stop in the vehicle's route, or -1
+	Visit   string // visit or order key, if any
+	Msg     string
+}
+
+func (v Violation) String() string {
+
+	s := v.Kind + ": "
+	if v.Vehicle != "" {
+		s += v.Vehicle + ": "
+	}
+	if v.Stop >= 0 {
+		s += fmt.Sprintf("stop %d: ", v.Stop)
+	}
+	if v.Visit != "" {
+		s += v.Visit + ": "
+	}
+
+	return s + v.Msg
+}
+
+// Report is the outcome of Verify: the violations found, and the lateness
+// and overtime of the schedule as recomputed from its times.
+type Report struct {
+	Violations    []Violation
+	NumLateVisits int
+	TotalLateness float32         // minutes
+	Overtime      VehicleOvertime // of the vehicles with overtime
+	TotalOvertime float32         // minutes
+}
+
+// OK reports whether the schedule keeps to its plan.
+func (r Report) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// Verify checks that a schedule, e.g. one edited by hand, still keeps to
+// its plan:
+//   - every visit is served exactly once or listed as unserved, and every
+//     order is picked up before it is dropped off, by the same vehicle;
+//   - every stop is served in a time window, for its whole duration, by a
+//     vehicle of the right type and within its shift;
+//   - every break is taken in its window;
+//   - no vehicle is ever loaded beyond its capacity;
+//   - the reported number of unserved and late visits, lateness and
+//     overtime match the schedule.
+//
+// Lateness and overtime are violations only beyond the maximum allowed by
+// the plan's options. If matrix is not nil, arrivals are also checked
+// against the travel times from the previous stop; the error is that of the
+// matrix. Routes may run past midnight, as placed by Materialize, but a stop
+// that arrives less than half a day before the stop before it is left is
+// taken to be a mistake.
+func Verify(ctx context.Context, plan Plan, s Schedule, matrix MatrixProvider) (Report, error) {
+
+	v := verifier{
+		plan:    plan,
+		options: planOptions(plan),
+		served:  map[string][]place{},
+	}
+	fleet := plan.Vehicles()
+	timed := s.Materialize(time.Time{}, time.UTC)
+
+	for _, key := range sortedKeys(s.Solution) {
+		vehicle, ok := fleet[key]
+		if !ok {
+			v.add("vehicle", key, -1, "", "not in the fleet")
+			continue
+		}
+		var travel [][]float64
+		if matrix != nil {
+			var err error
+			if travel, err = v.travel(ctx, matrix, key, s.Solution[key]); err != nil {
+				return Report{}, err
+			}
+		}
+		v.route(key, vehicle, s.Solution[key], newClocks(timed.Routes[key]), travel)
+	}
+
+	switch p := plan.(type) {
+	case VRPlan:
+		for _, key := range sortedKeys(p.Visits) {
+			v.served1(key, "", s.Unserved)
+		}
+		v.unserved(s.Unserved, func(key string) bool {
+			_, ok := p.Visits[key]
+			return ok
+		})
+	case PDPlan:
+		for _, key := range sortedKeys(p.Visits) {
+			v.order(key, s.Unserved)
+		}
+		v.unserved(s.Unserved, func(key string) bool {
+			_, ok := p.Visits[key]
+			return ok
+		})
+	}
+
+	v.totals(s)
+
+	return v.report, nil
+}
+
+// planOptions returns the options of plan.
+func planOptions(plan Plan) Options {
+
+	switch p := plan.(type) {
+	case VRPlan:
+		return p.Options
+	case PDPlan:
+		return p.Options
+	}
+
+	return Options{}
+}
+
+// place is where a visit is served.
+type place struct {
+	vehicle string
+	stop    int
+	typ     string // of the stop, e.g. "pickup"
+}
+
+// verifier collects the violations of a schedule.
+type verifier struct {
+	plan    Plan
+	options Options
+	served  map[string][]place // by visit key
+	report  Report
+}
+
+func (v *verifier) add(kind, vehicle string, stop int, visit, format string, args ...interface{}) {
+	v.report.Violations = append(v.report.Violations, Violation{
+		Kind:    kind,
+		Vehicle: vehicle,
+		Stop:    stop,
+		Visit:   visit,
+		Msg:     fmt.Sprintf(format, args...),
+	})
+}
+
+// travel returns the travel times between the stops of a route, with -1 for
+// stops that have no location.
+func (v *verifier) travel(
+	ctx context.Context,
+	matrix MatrixProvider,
+	key string,
+	stops Stops,
+) ([][]float64, error) {
+
+	index := make([]int, len(stops))
+	var locations []Location
+	for i := range stops {
+		index[i] = -1
+		if loc, ok := stopLocation(v.plan, key, stops, i); ok {
+			index[i] = len(locations)
+			locations = append(locations, loc)
+		}
+	}
+
+	m, err := matrix.Matrix(ctx, locations)
+	if err != nil {
+		return nil, err
+	}
+
+	travel := make([][]float64, len(stops))
+	for i := range stops {
+		travel[i] = make([]float64, len(stops))
+		for j := range stops {
+			travel[i][j] = -1
+			if index[i] >= 0 && index[j] >= 0 {
+				travel[i][j] = m.Durations[index[i]][index[j]]
+			}
+		}
+	}
+
+	return travel, nil
+}
+
+// clocks are the arrival and departure times of the stops of a route in
+// minutes since midnight of its first day, so that they keep increasing on
+// routes that run past midnight.
+type clocks struct {
+	arrive, leave []ClockTime
+}
+
+// newClocks returns the clocks of a route placed on the zero date in UTC.
+func newClocks(route []TimedStop) clocks {
+
+	c := clocks{
+		arrive: make([]ClockTime, len(route)),
+		leave:  make([]ClockTime, len(route)),
+	}
+	for i, stop := range route {
+		c.arrive[i] = ClockTime(stop.Arrival.Sub(time.Time{}) / time.Minute)
+		c.leave[i] = c.arrive[i]
+		if !stop.Finish.IsZero() {
+			c.leave[i] = ClockTime(stop.Finish.Sub(time.Time{}) / time.Minute)
+		}
+	}
+
+	return c
+}
+
+// route checks the route of a vehicle.
+func (v *verifier) route(key string, vehicle Vehicle, stops Stops, c clocks, travel [][]float64) {
+
+	var load Load
+	for _, stop := range stops {
+		if t, ok := v.plan.visitTerms(stop); ok && !stop.Break {
+			load = load.Add(t.preload)
+		}
+	}
+	overloaded := !load.Fits(vehicle.Capacity)
+	if overloaded {
+		v.add("capacity", key, 0, "", "load %s exceeds capacity %s",
+			load, vehicle.Capacity)
+	}
+
+	taken := map[string]bool{}
+	last := -1 // previous stop with a location
+
+	for i, stop := range stops {
+		if i > 0 {
+			v.arrival(key, stops, c, i, travel, last)
+		}
+		if _, ok := stopLocation(v.plan, key, stops, i); ok {
+			last = i
+		}
+
+		if stop.Break {
+			v.brk(key, vehicle, i, stop, c.leave[i]-c.arrive[i])
+			taken[stop.ID] = true
+			continue
+		}
+
+		t, ok := v.plan.visitTerms(stop)
+		if !ok {
+			if !v.isDepot(key, vehicle, stops, i) {
+				v.add("unknown", key, i, stop.ID, "not a visit of the plan")
+			}
+			continue
+		}
+		v.served[stop.ID] = append(v.served[stop.ID], place{key, i, stop.Type})
+
+		v.visit(key, vehicle, i, stop, c.leave[i]-c.arrive[i], t)
+
+		load = load.Add(t.change)
+		if !overloaded && !load.Fits(vehicle.Capacity) {
+			overloaded = true
+			v.add("capacity", key, i, stop.ID, "load %s exceeds capacity %s",
+				load, vehicle.Capacity)
+		}
+	}
+
+	v.shift(key, vehicle, stops, c)
+
+	hasVisits := false
+	for _, stop := range stops {
+		if _, ok := v.plan.visitTerms(stop); ok && !stop.Break {
+			hasVisits = true
+		}
+	}
+	for _, b := range vehicle.Breaks {
+		if hasVisits && !taken[b.ID] {
+			v.add("break", key, -1, "", "break %s is not taken", b.ID)
+		}
+	}
+}
+
+// isDepot reports whether stops[i] is the start or end depot of the
+// vehicle.
+func (v *verifier) isDepot(key string, vehicle Vehicle, stops Stops, i int) bool {
+
+	id := stops[i].ID
+	switch {
+	case id != "" && (id == vehicle.StartLocation.ID || id == vehicle.EndLocation.ID):
+		return true
+	case i == 0 || i == len(stops)-1:
+		_, ok := stopLocation(v.plan, key, stops, i)
+		return ok
+	}
+
+	return false
+}
+
+// departure returns when the vehicle leaves stop s.
+func departure(s Stop) ClockTime {
+
+	if s.HasFinish() {
+		return s.FinishTime
+	}
+
+	return s.ArrivalTime
+}
+
+// arrival checks that the vehicle arrives at stops[i] after leaving the
+// stop before, with enough time to drive from the previous stop with a
+// location.
+func (v *verifier) arrival(key string, stops Stops, c clocks, i int, travel [][]float64, last int) {
+
+	stop := stops[i]
+	left := c.leave[i-1]
+	if c.arrive[i]-left > minutesPerDay/2 {
+		v.add("travel", key, i, stop.ID, "arrives at %s, before leaving the stop before at %s",
+			stop.ArrivalTime, departure(stops[i-1]))
+		return
+	}
+
+	if travel == nil || last < 0 || travel[last][i] < 0 {
+		return
+	}
+	// Breaks without a location are taken on the way
+	for j := last + 1; j < i; j++ {
+		left -= c.leave[j] - c.arrive[j]
+	}
+	if need := travel[last][i]; float64(c.arrive[i]-left) < need-2*rounding {
+		v.add("travel", key, i, stop.ID, "arrives at %s, %g minutes after leaving %s, want %.1f",
+			stop.ArrivalTime, float64(c.arrive[i]-left), stops[last].ID, need)
+	}
+}
+
+// visit checks the time window, duration and vehicle type of a visit that
+// lasts the given minutes from arrival, and adds up its lateness.
+func (v *verifier) visit(key string, vehicle Vehicle, i int, stop Stop, length ClockTime, t terms) {
+
+	if len(t.types) > 0 && !contains(t.types, vehicle.Type) {
+		v.add("type", key, i, stop.ID, "vehicle type %q, want one of %q",
+			vehicle.Type, t.types)
+	}
+
+	start, late := serviceStart(stop.ArrivalTime, t.windows)
+	if late > 0 {
+		v.report.NumLateVisits++
+		v.report.TotalLateness += late
+		if late > v.options.MaxVisitLateness+rounding {
+			v.add("time_window", key, i, stop.ID, "%g minutes late at %s",
+				late, stop.ArrivalTime)
+		}
+	}
+
+	if !stop.HasFinish() || v.options.SquashDurations > 0 {
+		return
+	}
+	if wait := start - stop.ArrivalTime; float32(length-wait) < t.duration-rounding {
+		v.add("duration", key, i, stop.ID,
+			"finishes at %s, before service of %g minutes from %s ends",
+			stop.FinishTime, t.duration, start)
+	}
+}
+
+// serviceStart returns when service can start after arriving at t: when the
+// first time window that has not closed opens. If every window has closed,
+// service starts at once, late by the minutes since the last one closed.
+func serviceStart(t ClockTime, windows []TimeWindow) (ClockTime, float32) {
+
+	var lastEnd ClockTime
+	for _, w := range windows {
+		end := w.End
+		if end == 0 {
+			end = minutesPerDay
+		}
+		if t <= end {
+			if t < w.Start {
+				return w.Start, 0
+			}
+			return t, 0
+		}
+		if end > lastEnd {
+			lastEnd = end
+		}
+	}
+
+	if len(windows) == 0 {
+		return t, 0
+	}
+
+	return t, float32(t - lastEnd)
+}
+
+// brk checks that a break of the vehicle, lasting the given minutes, is
+// taken in its window.
+func (v *verifier) brk(key string, vehicle Vehicle, i int, stop Stop, length ClockTime) {
+
+	for _, b := range vehicle.Breaks {
+		if b.ID != stop.ID {
+			continue
+		}
+		end := stop.ArrivalTime + length
+		if stop.ArrivalTime < b.Start || b.End != 0 && end > b.End {
+			v.add("break", key, i, stop.ID, "taken from %s to %s, want between %s and %s",
+				stop.ArrivalTime, departure(stop), b.Start, b.End)
+		}
+		if float32(length) < b.Length()-rounding {
+			v.add("break", key, i, stop.ID, "lasts %d minutes, want %g",
+				int(length), b.Length())
+		}
+		return
+	}
+
+	v.add("unknown", key, i, stop.ID, "not a break of the vehicle")
+}
+
+// shift checks that the route keeps to the shift of the vehicle, and adds
+// up its overtime.
+func (v *verifier) shift(key string, vehicle Vehicle, stops Stops, c clocks) {
+
+	if len(stops) == 0 {
+		return
+	}
+
+	if start := c.leave[0]; start < vehicle.ShiftStart {
+		v.add("shift", key, 0, "", "leaves at %s, before the shift starts at %s",
+			departure(stops[0]), vehicle.ShiftStart)
+	}
+
+	if vehicle.ShiftEnd == 0 {
+		return
+	}
+	shiftEnd := vehicle.ShiftEnd
+	if shiftEnd < vehicle.ShiftStart {
+		shiftEnd += minutesPerDay // shift past midnight
+	}
+	last := len(stops) - 1
+	if c.leave[last] <= shiftEnd {
+		return
+	}
+
+	end := departure(stops[last])
+	overtime := float32(c.leave[last] - shiftEnd)
+	if v.report.Overtime == nil {
+		v.report.Overtime = VehicleOvertime{}
+	}
+	v.report.Overtime[key] = overtime
+	v.report.TotalOvertime += overtime
+	if overtime > v.options.MaxVehicleOvertime+rounding {
+		v.add("shift", key, len(stops)-1, "", "ends at %s, %g minutes after the shift",
+			end, overtime)
+	}
+}
+
+// served1 checks that the visit with the given key is served exactly once,
+// at a stop of type typ, or listed as unserved.
+func (v *verifier) served1(key, typ string, unserved map[string]string) []place {
+
+	var at []place
+	for _, p := range v.served[key] {
+		if p.typ == typ || typ == "" {
+			at = append(at, p)
+		}
+	}
+
+	_, listed := unserved[key]
+	name := "visit"
+	if typ != "" {
+		name = typ
+	}
+
+	switch {
+	case len(at) == 0 && !listed:
+		v.add("missing", "", -1, key, "%s neither served nor listed as unserved", name)
+	case len(at) > 0 && listed:
+		v.add("unserved", at[0].vehicle, at[0].stop, key,
+			"%s served but listed as unserved", name)
+	case len(at) > 1:
+		v.add("duplicate", at[1].vehicle, at[1].stop, key, "%s served %d times",
+			name, len(at))
+	}
+
+	return at
+}
+
+// order checks that an order is picked up and then dropped off by the same
+// vehicle, or listed as unserved.
+func (v *verifier) order(key string, unserved map[string]string) {
+
+	if len(v.served[key]) == 0 {
+		v.served1(key, "", unserved)
+		return
+	}
+
+	pickups := v.served1(key, "pickup", unserved)
+	dropoffs := v.served1(key, "dropoff", unserved)
+	if len(pickups) != 1 || len(dropoffs) != 1 {
+		return
+	}
+
+	p, d := pickups[0], dropoffs[0]
+	switch {
+	case p.vehicle != d.vehicle:
+		v.add("order", d.vehicle, d.stop, key, "dropped off by %s, picked up by %s",
+			d.vehicle, p.vehicle)
+	case d.stop < p.stop:
+		v.add("order", d.vehicle, d.stop, key, "dropped off before pickup at stop %d",
+			p.stop)
+	}
+}
+
+// unserved checks that the unserved visits are in the plan.
+func (v *verifier) unserved(unserved map[string]string, inPlan func(key string) bool) {
+
+	for _, key := range sortedKeys(unserved) {
+		if !inPlan(key) {
+			v.add("unknown", "", -1, key, "listed as unserved but not in the plan")
+		}
+	}
+}
+
+// totals checks the totals reported by the schedule.
+func (v *verifier) totals(s Schedule) {
+
+	r := &v.report
+
+	if s.NumUnserved != len(s.Unserved) {
+		v.add("total", "", -1, "", "num_unserved %d, want %d",
+			s.NumUnserved, len(s.Unserved))
+	}
+	if s.NumLateVisits != r.NumLateVisits {
+		v.add("total", "", -1, "", "num_late_visits %d, want %d",
+			s.NumLateVisits, r.NumLateVisits)
+	}
+	if math.Abs(float64(s.TotalLateness-r.TotalLateness)) > rounding {
+		v.add("total", "", -1, "", "total_visit_lateness %g, want %g",
+			s.TotalLateness, r.TotalLateness)
+	}
+	if math.Abs(float64(s.TotalOvertime-r.TotalOvertime)) > rounding {
+		v.add("total", "", -1, "", "total_overtime %g, want %g",
+			s.TotalOvertime, r.TotalOvertime)
+	}
+}
+
+func contains(list []string, s string) bool {
+
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
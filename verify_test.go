@@ -0,0 +1,322 @@
+package routific_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	r "github.com/slamethendry/routific"
+	"github.com/stretchr/testify/assert"
+)
+
+// verify_test checks that Verify finds what a schedule breaks of its plan.
+// Test data is defined in setup_test.
+
+// verifyRoute is a timed route of vrpInput.
+var verifyRoute = r.Stops{
+	{ID: "depot", Name: "800 Kingsway", ArrivalTime: r.Clock(8, 0)},
+	{ID: "order_3", Name: "800 Robson", ArrivalTime: r.Clock(8, 10),
+		FinishTime: r.Clock(8, 20)},
+	{ID: "order_2", Name: "3780 Arbutus", ArrivalTime: r.Clock(8, 30),
+		FinishTime: r.Clock(8, 40)},
+	{ID: "order_1", Name: "6800 Cambie", ArrivalTime: r.Clock(8, 50),
+		FinishTime: r.Clock(9, 0)},
+	{ID: "depot", Name: "800 Kingsway", ArrivalTime: r.Clock(9, 10)},
+}
+
+// withRoute returns a schedule of the single route of vehicle_1.
+func withRoute(stops r.Stops) r.Schedule {
+	return r.Schedule{Solution: map[string]r.Stops{"vehicle_1": stops}}
+}
+
+// edit returns a copy of stops changed by fn.
+func edit(stops r.Stops, fn func(r.Stops) r.Stops) r.Stops {
+	return fn(append(r.Stops(nil), stops...))
+}
+
+// violations returns the kinds of the violations of the report.
+func violations(report r.Report) []string {
+
+	var out []string
+	for _, v := range report.Violations {
+		out = append(out, v.Kind)
+	}
+
+	return out
+}
+
+func verify(t *testing.T, plan r.Plan, s r.Schedule) r.Report {
+
+	t.Helper()
+	report, err := r.Verify(context.Background(), plan, s, nil)
+	assert.Nil(t, err)
+
+	return report
+}
+
+func TestVerifyVRP(t *testing.T) {
+
+	report := verify(t, vrpInput, withRoute(verifyRoute))
+	assert.True(t, report.OK(), report.Violations)
+
+	missing := edit(verifyRoute, func(s r.Stops) r.Stops {
+		return append(s[:3], s[4])
+	})
+	report = verify(t, vrpInput, withRoute(missing))
+	assert.Equal(t, []string{"missing"}, violations(report))
+	assert.Equal(t, "order_1", report.Violations[0].Visit)
+	assert.Equal(t, "missing: order_1: visit neither served nor listed as unserved",
+		report.Violations[0].String())
+
+	s := withRoute(missing)
+	s.Unserved = map[string]string{"order_1": "No vehicle available"}
+	s.NumUnserved = 1
+	assert.True(t, verify(t, vrpInput, s).OK())
+
+	s.Unserved["order_9"] = "Unknown"
+	s.Solution["vehicle_1"] = verifyRoute
+	assert.Equal(t, []string{"unserved", "unknown", "total"}, violations(verify(t, vrpInput, s)))
+
+	twice := edit(verifyRoute, func(s r.Stops) r.Stops {
+		s[3] = s[2]
+		return s
+	})
+	report = verify(t, vrpInput, withRoute(twice))
+	assert.ElementsMatch(t, []string{"duplicate", "missing", "travel"}, violations(report))
+
+	unknown := edit(verifyRoute, func(s r.Stops) r.Stops {
+		s[3].ID = "order_9"
+		return s
+	})
+	report = verify(t, vrpInput, withRoute(unknown))
+	assert.Equal(t, []string{"unknown", "missing"}, violations(report))
+	assert.Equal(t, 3, report.Violations[0].Stop)
+
+	s = withRoute(verifyRoute)
+	s.Solution["vehicle_9"] = verifyRoute
+	assert.Equal(t, []string{"vehicle"}, violations(verify(t, vrpInput, s)))
+}
+
+func TestVerifyVRPTerms(t *testing.T) {
+
+	plan := r.VRPlan{Visits: map[string]r.Visit{}, Fleet: map[string]r.Vehicle{}}
+	for k, v := range vrpInput.Visits {
+		plan.Visits[k] = v
+	}
+	vehicle := vrpInput.Fleet["vehicle_1"]
+	vehicle.ShiftStart = r.Clock(8, 0)
+	vehicle.ShiftEnd = r.Clock(9, 0)
+	vehicle.Capacity = r.Units(2)
+	plan.Fleet["vehicle_1"] = vehicle
+
+	order1 := plan.Visits["order_1"]
+	order1.Type = "van"
+	order1.TimeWindows = []r.TimeWindow{
+		{Start: r.Clock(8, 0), End: r.Clock(8, 10)},
+		{Start: r.Clock(8, 30), End: r.Clock(8, 45)},
+	}
+	plan.Visits["order_1"] = order1
+
+	order2 := plan.Visits["order_2"]
+	order2.Duration = 15
+	plan.Visits["order_2"] = order2
+
+	report := verify(t, plan, withRoute(verifyRoute))
+	assert.Equal(t, []string{
+		"capacity", "duration", "type", "time_window", "shift", "total", "total", "total",
+	}, violations(report))
+	assert.Equal(t, 1, report.NumLateVisits)
+	assert.Equal(t, float32(5), report.TotalLateness)
+	assert.Equal(t, r.VehicleOvertime{"vehicle_1": 10}, report.Overtime)
+	assert.Equal(t, float32(10), report.TotalOvertime)
+
+	// Within the allowed lateness and overtime, reported as such
+	plan.Options.MaxVisitLateness = 5
+	plan.Options.MaxVehicleOvertime = 15
+	s := withRoute(verifyRoute)
+	s.NumLateVisits = 1
+	s.TotalLateness = 5
+	s.TotalOvertime = 10
+	assert.Equal(t, []string{"capacity", "duration", "type"}, violations(verify(t, plan, s)))
+}
+
+func TestVerifyPastMidnight(t *testing.T) {
+
+	plan := r.VRPlan{Visits: map[string]r.Visit{}, Fleet: map[string]r.Vehicle{}}
+	for k, v := range vrpInput.Visits {
+		v.Duration = 10
+		plan.Visits[k] = v
+	}
+	vehicle := vrpInput.Fleet["vehicle_1"]
+	vehicle.ShiftStart = r.Clock(23, 0)
+	vehicle.ShiftEnd = r.Clock(0, 30)
+	plan.Fleet["vehicle_1"] = vehicle
+
+	night := r.Stops{
+		{ID: "depot", Name: "800 Kingsway", ArrivalTime: r.Clock(23, 15)},
+		{ID: "order_3", Name: "800 Robson", ArrivalTime: r.Clock(23, 25),
+			FinishTime: r.Clock(23, 35)},
+		{ID: "order_2", Name: "3780 Arbutus", ArrivalTime: r.Clock(23, 45),
+			FinishTime: r.Clock(23, 55)},
+		{ID: "order_1", Name: "6800 Cambie", ArrivalTime: r.Clock(23, 55),
+			FinishTime: r.Clock(0, 5)},
+		{ID: "depot", Name: "800 Kingsway", ArrivalTime: r.Clock(0, 15)},
+	}
+	report := verify(t, plan, withRoute(night))
+	assert.True(t, report.OK(), report.Violations)
+
+	// Cut short at midnight
+	short := edit(night, func(s r.Stops) r.Stops {
+		s[3].FinishTime, s[3].FinishAtMidnight = 0, true
+		return s
+	})
+	report = verify(t, plan, withRoute(short))
+	assert.Equal(t, []string{"duration"}, violations(report))
+	assert.Equal(t, 3, report.Violations[0].Stop)
+
+	// Back after the shift ends
+	late := edit(night, func(s r.Stops) r.Stops {
+		s[4].ArrivalTime = r.Clock(0, 45)
+		return s
+	})
+	report = verify(t, plan, withRoute(late))
+	assert.Equal(t, []string{"shift", "total"}, violations(report))
+	assert.Equal(t, r.VehicleOvertime{"vehicle_1": 15}, report.Overtime)
+}
+
+func TestVerifyBreaks(t *testing.T) {
+
+	plan := r.VRPlan{Visits: vrpInput.Visits, Fleet: map[string]r.Vehicle{}}
+	vehicle := vrpInput.Fleet["vehicle_1"]
+	vehicle.Breaks = []r.Break{{
+		ID: "lunch", Start: r.Clock(8, 30), End: r.Clock(10, 0), Duration: 30,
+	}}
+	plan.Fleet["vehicle_1"] = vehicle
+
+	report := verify(t, plan, withRoute(verifyRoute))
+	assert.Equal(t, []string{"break"}, violations(report))
+	assert.Equal(t, "break: vehicle_1: break lunch is not taken", report.Violations[0].String())
+
+	lunch := r.Stop{ID: "lunch", ArrivalTime: r.Clock(9, 0), FinishTime: r.Clock(9, 30),
+		Break: true}
+	taken := edit(verifyRoute, func(s r.Stops) r.Stops {
+		s = append(s[:4:4], lunch, s[4])
+		s[5].ArrivalTime = r.Clock(9, 40)
+		return s
+	})
+	assert.True(t, verify(t, plan, withRoute(taken)).OK())
+
+	// Too short, then too early
+	short := edit(taken, func(s r.Stops) r.Stops {
+		s[4].FinishTime = r.Clock(9, 15)
+		return s
+	})
+	assert.Equal(t, []string{"break"}, violations(verify(t, plan, withRoute(short))))
+
+	early := edit(verifyRoute, func(s r.Stops) r.Stops {
+		lunch := lunch
+		lunch.ArrivalTime, lunch.FinishTime = r.Clock(8, 20), r.Clock(8, 50)
+		s = append(s[:2:2], lunch, s[2], s[3], s[4])
+		s[3].ArrivalTime, s[3].FinishTime = r.Clock(9, 0), r.Clock(9, 10)
+		s[4].ArrivalTime, s[4].FinishTime = r.Clock(9, 20), r.Clock(9, 30)
+		s[5].ArrivalTime = r.Clock(9, 40)
+		return s
+	})
+	report = verify(t, plan, withRoute(early))
+	assert.Equal(t, []string{"break"}, violations(report))
+	assert.Equal(t, 2, report.Violations[0].Stop)
+
+	unknown := edit(taken, func(s r.Stops) r.Stops {
+		s[4].ID = "dinner"
+		return s
+	})
+	assert.Equal(t, []string{"unknown", "break"}, violations(verify(t, plan, withRoute(unknown))))
+}
+
+func TestVerifyPDP(t *testing.T) {
+
+	report := verify(t, pdpInput, pdpOutput)
+	assert.True(t, report.OK(), report.Violations)
+
+	// Dropped off before pickup
+	s := r.Schedule{Solution: map[string]r.Stops{
+		"vehicle_1": edit(pdpRoute1, func(s r.Stops) r.Stops {
+			s[2], s[3] = s[3], s[2]
+			s[2].ArrivalTime, s[3].ArrivalTime = s[3].ArrivalTime, s[2].ArrivalTime
+			s[2].FinishTime, s[3].FinishTime = s[3].FinishTime, s[2].FinishTime
+			return s
+		}),
+		"vehicle_2": pdpRoute2,
+	}}
+	report = verify(t, pdpInput, s)
+	assert.Equal(t, []string{"order"}, violations(report))
+	assert.Equal(t, r.Violation{
+		Kind:    "order",
+		Vehicle: "vehicle_1",
+		Stop:    2,
+		Visit:   "order_1",
+		Msg:     "dropped off before pickup at stop 3",
+	}, report.Violations[0])
+
+	// Dropped off by another vehicle, which is not loaded with it
+	s = r.Schedule{Solution: map[string]r.Stops{
+		"vehicle_1": edit(pdpRoute1, func(s r.Stops) r.Stops {
+			return append(s[:3], s[4:]...)
+		}),
+		"vehicle_2": {
+			pdpRoute2[0],
+			pdpRoute1[3],
+			{ID: "depot", Name: "800 Kingsway", ArrivalTime: r.Clock(9, 45)},
+		},
+	}}
+	report = verify(t, pdpInput, s)
+	assert.Equal(t, []string{"order"}, violations(report))
+
+	// Pickup without dropoff
+	s = r.Schedule{Solution: map[string]r.Stops{
+		"vehicle_1": edit(pdpRoute1, func(s r.Stops) r.Stops {
+			return append(s[:4], s[5])
+		}),
+		"vehicle_2": pdpRoute2,
+	}}
+	report = verify(t, pdpInput, s)
+	assert.Equal(t, []string{"missing"}, violations(report))
+	assert.Equal(t, "missing: order_2: dropoff neither served nor listed as unserved",
+		report.Violations[0].String())
+
+	// Both orders on board at once
+	small := pdpInput
+	small.Fleet = map[string]r.Vehicle{"vehicle_1": pdpInput.Fleet["vehicle_2"]}
+	small.Fleet["vehicle_1"] = r.Vehicle{
+		StartLocation: kingswayDepot,
+		EndLocation:   kingswayDepot,
+		Capacity:      r.Units(1),
+	}
+	report = verify(t, small, withRoute(pdpRoute1))
+	assert.Equal(t, []string{"capacity"}, violations(report))
+	assert.Equal(t, 2, report.Violations[0].Stop)
+}
+
+func TestVerifyMatrix(t *testing.T) {
+
+	ctx := context.Background()
+
+	fast := r.Haversine{Circuity: 1, Speed: r.ConstantSpeed(60)}
+	report, err := r.Verify(ctx, pdpInput, pdpOutput, fast)
+	assert.Nil(t, err)
+	assert.True(t, report.OK(), report.Violations)
+
+	slow := r.Haversine{Circuity: 1, Speed: r.ConstantSpeed(20)}
+	report, err = r.Verify(ctx, pdpInput, pdpOutput, slow)
+	assert.Nil(t, err)
+	assert.Contains(t, violations(report), "travel")
+	for _, v := range report.Violations {
+		assert.Equal(t, "travel", v.Kind)
+	}
+
+	failing := r.MatrixFunc(func(context.Context, []r.Location) (r.Matrix, error) {
+		return r.Matrix{}, r.ErrNotSupported
+	})
+	_, err = r.Verify(ctx, pdpInput, pdpOutput, failing)
+	assert.True(t, errors.Is(err, r.ErrNotSupported))
+}